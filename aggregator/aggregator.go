@@ -69,3 +69,46 @@ func (s aggregatedStats) Stats() []TimestampedStat {
 
 // Vpp UUID type - uniquely identifying a VPP instance (host)
 type VppUuid string
+
+// Stat tagged with the VPP instance it originates from. Aggregators shared by multiple VPP instances
+// receive stats as OriginStat, plain stats are tagged with the uuid the aggregator was started with.
+type OriginStat struct {
+	VppUuid VppUuid
+	Stat    Stat
+}
+
+// Collector facing side of an aggregator tagging all stats with a VPP uuid. Has to be closed before the wrapped
+// aggregator, so that no stat is forwarded into its closed channel.
+type OriginAggregator interface {
+	CollectorAggregator
+	// Stop forwarding, waits for a stat being forwarded. No stat may be sent to the channel once closed.
+	Close()
+}
+
+type originAggregator struct {
+	channel chan (Stat)
+	done    chan (int)
+}
+
+func (s *originAggregator) Channel() chan (Stat) {
+	return s.channel
+}
+
+func (s *originAggregator) Close() {
+	close(s.channel)
+	<-s.done
+}
+
+// Wrap aggregator, so that all stats sent through the wrapper are tagged with uuid
+func WithOrigin(aggregator CollectorAggregator, uuid VppUuid) OriginAggregator {
+	wrapper := &originAggregator{channel: make(chan Stat), done: make(chan (int))}
+
+	go func() {
+		defer close(wrapper.done)
+		for stat := range wrapper.channel {
+			aggregator.Channel() <- OriginStat{VppUuid: uuid, Stat: stat}
+		}
+	}()
+
+	return wrapper
+}
@@ -21,7 +21,7 @@ type bufferedAggregator struct {
 	outboundChannelsLock sync.Mutex
 	outboundChannels     [](chan (AggregatedStat))
 	configuration        BufferedAggregatorConfiguration
-	stripCheck           func(stat Stat) bool
+	stripCheck           func(uuid VppUuid, stat Stat) bool
 }
 
 func (s BufferedAggregatorConfiguration) Create() Aggregator {
@@ -38,7 +38,7 @@ func (s BufferedAggregatorConfiguration) Create() Aggregator {
 	return &bufferedAggregator{
 		inboundChannel: make(chan Stat, int(s.InboundBufferSize)),
 		configuration:  s,
-		stripCheck: func(stat Stat) bool {
+		stripCheck: func(uuid VppUuid, stat Stat) bool {
 			return false
 		},
 	}
@@ -116,7 +116,13 @@ infiniteLoop:
 				break collectLoop
 			}
 
-			if s.stripCheck(stat) {
+			statUuid := uuid
+			if origin, isOrigin := stat.(OriginStat); isOrigin {
+				statUuid = origin.VppUuid
+				stat = origin.Stat
+			}
+
+			if s.stripCheck(statUuid, stat) {
 				log.WithFields(log.Fields{
 					"type": reflect.TypeOf(stat),
 					"stat": util.StringOf(stat),
@@ -126,7 +132,7 @@ infiniteLoop:
 
 			log.WithField("stat", util.StringOf(stat)).Info("Stat received, aggregating")
 			statSlice = append(statSlice, TimestampedStat{
-				VppUuid:   uuid,
+				VppUuid:   statUuid,
 				Timestamp: time.Now(),
 				StatType:  reflect.TypeOf(stat).String(),
 				Stat:      stat,
@@ -13,18 +13,25 @@ type FilteringAggregatorConfiguration struct {
 
 type filteringAggregator struct {
 	*bufferedAggregator
-	cache map[reflect.Type]interface{}
+	cache map[cacheKey]interface{}
+}
+
+// Previous values are cached per VPP instance and stat type
+type cacheKey struct {
+	uuid     VppUuid
+	statType reflect.Type
 }
 
 func (s FilteringAggregatorConfiguration) Create() Aggregator {
-	cache := make(map[reflect.Type]interface{})
+	cache := make(map[cacheKey]interface{})
 	delegateAggregator := &bufferedAggregator{
 		inboundChannel: make(chan Stat, int(s.InboundBufferSize)),
 		configuration:  s.BufferedAggregatorConfiguration,
 
 		// Check if previous value received for the type is equal and if so ignore it
-		stripCheck: func(stat Stat) bool {
-			if value, isPresent := cache[reflect.TypeOf(stat)]; isPresent {
+		stripCheck: func(uuid VppUuid, stat Stat) bool {
+			key := cacheKey{uuid, reflect.TypeOf(stat)}
+			if value, isPresent := cache[key]; isPresent {
 
 				// If equal with previous then discard
 				if util.StringOf(value) == util.StringOf(stat) {
@@ -33,7 +40,7 @@ func (s FilteringAggregatorConfiguration) Create() Aggregator {
 			}
 
 			// Cache and accept
-			cache[reflect.TypeOf(stat)] = stat
+			cache[key] = stat
 			return false
 		},
 	}
@@ -56,3 +56,55 @@ loop:
 		}
 	}
 }
+
+func TestFilteringMultipleOrigins(t *testing.T) {
+	aggr := FilteringAggregatorConfiguration{
+		BufferedAggregatorConfiguration{
+			InboundBufferSize:  0,
+			OutboundBufferSize: 10,
+			Name:               "Test",
+		}}.Create()
+
+	aggr.Start(UUID)
+	reg := aggr.Register()
+	defer aggr.Close()
+
+	// Identical stats from distinct VPPs must not be filtered
+	vpp1 := WithOrigin(aggr, "vpp1")
+	vpp2 := WithOrigin(aggr, "vpp2")
+	// Wrappers are stopped before the aggregator, once all stats were sent
+	sent := make(chan (int))
+	defer func() {
+		<-sent
+		vpp1.Close()
+		vpp2.Close()
+	}()
+	go func() {
+		defer close(sent)
+		vpp1.Channel() <- stat
+		vpp2.Channel() <- stat
+		vpp1.Channel() <- stat
+	}()
+
+	received := make(map[VppUuid]int)
+	for len(received) < 2 {
+		select {
+		case aggrStat := <-reg.Channel():
+			for _, s := range aggrStat.Stats() {
+				received[s.VppUuid]++
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatalf("Timed out. Stats received: %v", received)
+		}
+	}
+
+	select {
+	case aggrStat := <-reg.Channel():
+		t.Errorf("Repeated stat not filtered: %v", aggrStat.Stats())
+	case <-time.After(time.Millisecond * time.Duration(TIMEOUT)):
+	}
+
+	if received["vpp1"] != 1 || received["vpp2"] != 1 {
+		t.Errorf("Unexpected stats received: %v", received)
+	}
+}
@@ -0,0 +1,55 @@
+package api_stats
+
+import (
+	"context"
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/util"
+	"time"
+)
+
+// Reports metrics of the agent's own VPP API usage (request counts, reply latencies, timeouts, errors)
+type ApiStatsCollectorConfiguration struct {
+	Name string
+}
+
+func (s ApiStatsCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator) collector.Collector {
+	clctr := apiStatsCollector{
+		configuration: s,
+		aggregator:    aggregator,
+	}
+
+	log.WithFields(log.Fields{
+		"collector": clctr,
+	}).Debug("ApiStatsCollector created successfully")
+
+	return clctr
+}
+
+type apiStatsCollector struct {
+	configuration ApiStatsCollectorConfiguration
+	aggregator    aggregator.CollectorAggregator
+}
+
+type agentApiStats struct {
+	// Start of recording, metrics are kept across reconnects to the same VPP
+	Since time.Time `json:"since"`
+	// Metrics keyed by request message name
+	Messages map[string]govpp.MessageMetrics `json:"messages"`
+}
+
+func (s apiStatsCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	stats := agentApiStats{connection.MetricsSince(), connection.Metrics()}
+
+	log.WithFields(log.Fields{
+		"api-stats": util.StringOf(stats),
+	}).Debug("Agent API stats collected successfully")
+
+	s.aggregator.Channel() <- stats
+	return nil
+}
+
+func (s apiStatsCollector) Close() {
+}
@@ -0,0 +1,91 @@
+package api_stats
+
+import (
+	"context"
+	"github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	logrus.SetLevel(logrus.PanicLevel)
+}
+
+func TestApiStats(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+
+	// One successful, one failed and one timed out request
+	connection.SendRequest(context.Background(), &api.ShowVersion{})
+	server.RegisterHandler(&api.ShowVersion{}, func(uint32, api.Message) []api.Message {
+		return []api.Message{&api.ShowVersionReply{Retval: -1}}
+	})
+	connection.SendRequest(context.Background(), &api.ShowVersion{})
+	server.RegisterHandler(&api.ShowVersion{}, func(uint32, api.Message) []api.Message {
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(100))
+	connection.SendRequest(ctx, &api.ShowVersion{})
+	cancel()
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := ApiStatsCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+
+	stats := (<-aggr.Ch).(agentApiStats)
+	version := stats.Messages["show_version"]
+	if version.Requests != 3 || version.Replies != 2 || version.Errors != 1 || version.Timeouts != 1 {
+		t.Errorf("Unexpected show_version metrics: %+v", version)
+	}
+
+	var histogramCount uint64
+	for _, count := range version.LatencyHistogram {
+		histogramCount += count
+	}
+	if histogramCount != version.Replies {
+		t.Errorf("Latency histogram does not match replies: %+v", version)
+	}
+
+	// Initial ping
+	if ping := stats.Messages["control_ping"]; ping.Requests != 1 || ping.Replies != 1 {
+		t.Errorf("Unexpected control_ping metrics: %+v", ping)
+	}
+}
+
+func TestApiStatsSurviveReconnect(t *testing.T) {
+	server, _ := vpptest.NewConnectedServer(t)
+
+	attempt := govpp.VppConnectionAttempt{Name: "test", SocketPath: server.SocketPath(), Metrics: govpp.NewApiMetrics()}
+	first := attempt.Connect(context.Background())
+	first.SendRequest(context.Background(), &api.ShowVersion{})
+	first.Disconnect()
+
+	second := attempt.Connect(context.Background())
+	defer second.Disconnect()
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := ApiStatsCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), second); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+
+	stats := (<-aggr.Ch).(agentApiStats)
+	if version := stats.Messages["show_version"]; version.Requests != 1 {
+		t.Errorf("Metrics of the previous connection lost: %+v", version)
+	}
+	// Initial ping of each connection
+	if ping := stats.Messages["control_ping"]; ping.Requests != 2 {
+		t.Errorf("Unexpected control_ping metrics: %+v", ping)
+	}
+	if !stats.Since.Equal(first.MetricsSince()) {
+		t.Errorf("Unexpected start of recording: %v, expected: %v", stats.Since, first.MetricsSince())
+	}
+}
@@ -1,17 +1,38 @@
 package collector
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"pnda/vpp/monitoring/aggregator"
 	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"sync"
 	"time"
 )
 
 type Collector interface {
-	Collect(connection *govpp.VppConnection)
+	// Collect stats using connection. All VPP calls are bound by ctx and any failure is returned as an error.
+	// Collect is executed again after a VPP reconnect, so subscriptions to VPP notifications are made on the
+	// first call only and later calls just re-enable the notifications.
+	Collect(ctx context.Context, connection *govpp.VppConnection) error
 	Close()
 }
 
+// Collector declaring VPP API messages it depends on. Collectors incompatible with connected VPP are not executed.
+type MessageDependent interface {
+	Messages() []api.Message
+}
+
+// Returns an error if clctr depends on messages missing in connected VPP or having a different definition there
+func CheckCompatibility(connection *govpp.VppConnection, clctr Collector) error {
+	if dependent, isDependent := clctr.(MessageDependent); isDependent {
+		return connection.CheckCompatibility(dependent.Messages()...)
+	}
+	return nil
+}
+
 type CollectorConfiguration interface {
 	Create(aggregator aggregator.CollectorAggregator) Collector
 }
@@ -21,24 +42,124 @@ const NOTIFICATION_SCHEDULING = "notifications"
 const ONCE_SCHEDULING = "once"
 const REPEATED_SCHEDULING = "scheduled"
 
-func CollectOnce(connection *govpp.VppConnection, clctr Collector) {
+// Default time limit for a single collector execution
+const DEFAULT_TIMEOUT = time.Second * 5
+
+// Default delay between attempts of a failed collector execution
+const DEFAULT_RETRY_DELAY = time.Second
+
+// Execution settings of a collector
+type Execution struct {
+	Name string
+	// VPP target the collector is executed for
+	Target string
+	// Time limit for a single execution (all its VPP calls)
+	Timeout time.Duration
+	// Number of additional attempts after a failed execution
+	Retries uint
+	// Delay between attempts
+	RetryDelay time.Duration
+}
+
+// Identification of a collector, collectors of different VPP targets may share a name
+type failureKey struct {
+	target string
+	name   string
+}
+
+// Number of failed executions per collector
+var failures = struct {
+	sync.Mutex
+	counts map[failureKey]uint64
+}{counts: make(map[failureKey]uint64)}
+
+// Returns number of failed executions (including retries) per VPP target and collector name
+func Failures() map[string]map[string]uint64 {
+	failures.Lock()
+	defer failures.Unlock()
+
+	counts := make(map[string]map[string]uint64)
+	for key, count := range failures.counts {
+		if counts[key.target] == nil {
+			counts[key.target] = make(map[string]uint64)
+		}
+		counts[key.target][key.name] = count
+	}
+	return counts
+}
+
+// Execute collector, retrying failed executions as configured in execution
+func CollectOnce(connection *govpp.VppConnection, clctr Collector, execution Execution) {
+	collectWithRetries(connection, clctr, execution, nil)
+}
+
+// Execute collector, retrying failed executions as configured in execution. Waiting for a retry is interrupted
+// by a signal on stopChannel (nil never interrupts), returns false in that case.
+func collectWithRetries(connection *govpp.VppConnection, clctr Collector, execution Execution,
+	stopChannel chan (int)) bool {
+
 	log.WithFields(log.Fields{
 		"collector": clctr,
 	}).Info("Executing collector")
 
+	key := failureKey{execution.Target, execution.Name}
+	for attempt := uint(0); ; attempt++ {
+		err := collect(connection, clctr, execution.Timeout)
+		if err == nil {
+			return true
+		}
+
+		failures.Lock()
+		failures.counts[key]++
+		failureCount := failures.counts[key]
+		failures.Unlock()
+
+		fields := log.Fields{
+			"collector": clctr,
+			"component": execution.Name,
+			"target":    execution.Target,
+			"attempt":   attempt + 1,
+			"failures":  failureCount,
+			"error":     err,
+		}
+		var apiErr *api.VppApiError
+		if errors.As(err, &apiErr) {
+			fields["vpp-error"] = apiErr.Name()
+		}
+
+		if attempt >= execution.Retries {
+			log.WithFields(fields).Error("Collector execution failed")
+			return true
+		}
+
+		log.WithFields(fields).Warn("Collector execution failed, retrying")
+		select {
+		case <-stopChannel:
+			return false
+		case <-time.After(execution.RetryDelay):
+		}
+	}
+}
+
+// Single execution bound by timeout, a panic is returned as an error
+func collect(connection *govpp.VppConnection, clctr Collector, timeout time.Duration) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			log.WithFields(log.Fields{
-				"collector": clctr,
-				"panic":     r,
-			}).Error("Collector execution failed")
+			err = fmt.Errorf("Collector panicked: %v", r)
 		}
 	}()
 
-	clctr.Collect(connection)
+	if timeout <= 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return clctr.Collect(ctx, connection)
 }
 
-func CollectScheduled(connection *govpp.VppConnection, clctr Collector, delayInSeconds uint, stopChannel chan (int)) {
+func CollectScheduled(connection *govpp.VppConnection, clctr Collector, delayInSeconds uint, execution Execution,
+	stopChannel chan (int)) {
 	go func() {
 
 		log.WithFields(log.Fields{
@@ -48,19 +169,22 @@ func CollectScheduled(connection *govpp.VppConnection, clctr Collector, delayInS
 
 	loop:
 		for {
-			CollectOnce(connection, clctr)
+			if !collectWithRetries(connection, clctr, execution, stopChannel) {
+				break loop
+			}
 
 			select {
 			case <-stopChannel:
-				log.WithFields(log.Fields{
-					"collector": clctr,
-				}).Debug("Stopping exeuction")
-				close(stopChannel)
 				break loop
 
 			case <-time.After(time.Second * time.Duration(delayInSeconds)):
 				// Just a regular schedule
 			}
 		}
+
+		log.WithFields(log.Fields{
+			"collector": clctr,
+		}).Debug("Stopping exeuction")
+		close(stopChannel)
 	}()
 }
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/govpp"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func init() {
+	logrus.SetLevel(logrus.PanicLevel)
+}
+
+// Collector failing (with an error or a panic) a number of times before succeeding
+type failingCollector struct {
+	failures   int
+	executions int
+}
+
+func (s *failingCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	s.executions++
+	if s.executions > s.failures {
+		return nil
+	}
+	if s.executions%2 == 0 {
+		panic("failure")
+	}
+	return fmt.Errorf("failure")
+}
+
+func (s *failingCollector) Close() {
+}
+
+// Forget failures of previous tests (and previous runs with -count)
+func resetFailures() {
+	failures.Lock()
+	defer failures.Unlock()
+	failures.counts = make(map[failureKey]uint64)
+}
+
+func TestCollectOnceRetries(t *testing.T) {
+	resetFailures()
+	clctr := &failingCollector{failures: 2}
+	CollectOnce(nil, clctr, Execution{Name: "Retried", Target: "vpp", Retries: 2, RetryDelay: time.Millisecond})
+
+	if clctr.executions != 3 {
+		t.Errorf("Unexpected number of executions: %v", clctr.executions)
+	}
+	if failures := Failures()["vpp"]["Retried"]; failures != 2 {
+		t.Errorf("Unexpected number of failures: %v", failures)
+	}
+}
+
+func TestCollectOnceGivesUp(t *testing.T) {
+	resetFailures()
+	clctr := &failingCollector{failures: 5}
+	CollectOnce(nil, clctr, Execution{Name: "Failing", Target: "vpp", Retries: 1, RetryDelay: time.Millisecond})
+
+	if clctr.executions != 2 {
+		t.Errorf("Unexpected number of executions: %v", clctr.executions)
+	}
+	if failures := Failures()["vpp"]["Failing"]; failures != 2 {
+		t.Errorf("Unexpected number of failures: %v", failures)
+	}
+}
+
+// Collectors of different targets sharing a name are counted separately
+func TestFailuresPerTarget(t *testing.T) {
+	resetFailures()
+	CollectOnce(nil, &failingCollector{failures: 1}, Execution{Name: "Shared", Target: "first"})
+	CollectOnce(nil, &failingCollector{failures: 1}, Execution{Name: "Shared", Target: "second",
+		Retries: 1, RetryDelay: time.Millisecond})
+
+	expected := map[string]map[string]uint64{"first": {"Shared": 1}, "second": {"Shared": 1}}
+	if failures := Failures(); !reflect.DeepEqual(failures, expected) {
+		t.Errorf("Unexpected failures, expected: %v, received: %v", expected, failures)
+	}
+}
+
+// Stopping a scheduled collector does not wait for its retries
+func TestCollectScheduledStopsDuringRetry(t *testing.T) {
+	resetFailures()
+	stopCh := make(chan (int))
+	CollectScheduled(nil, &failingCollector{failures: 5}, 1,
+		Execution{Name: "Stopped", Retries: 5, RetryDelay: time.Hour}, stopCh)
+
+	select {
+	case stopCh <- -1:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Stop signal not accepted while waiting for a retry")
+	}
+
+	select {
+	case <-stopCh:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Scheduled execution not stopped")
+	}
+}
@@ -0,0 +1,170 @@
+package error_counters
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/util"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const SHOW_ERRORS = "show errors"
+
+type ErrorCountersCollectorConfiguration struct {
+	Name string
+}
+
+func (s ErrorCountersCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator) collector.Collector {
+	clctr := &errorCountersCollector{
+		configuration: s,
+		aggregator:    aggregator,
+	}
+
+	log.WithFields(log.Fields{
+		"collector": clctr,
+	}).Debug("ErrorCountersCollector created successfully")
+
+	return clctr
+}
+
+type errorCountersCollector struct {
+	configuration ErrorCountersCollectorConfiguration
+	aggregator    aggregator.CollectorAggregator
+	// Counts from the previous execution, nil until the first execution recorded the baseline
+	previous map[errorKey]uint64
+}
+
+type errorKey struct {
+	node   string
+	reason string
+}
+
+// Error counter of a graph node, e.g. ip4-input: ip4 ttl <= 1
+type errorCounter struct {
+	Node   string `json:"node"`
+	Reason string `json:"reason"`
+	Count  uint64 `json:"count"`
+	// Increase since the previous execution
+	Delta uint64 `json:"delta"`
+}
+
+type errorCounters struct {
+	Counters []errorCounter `json:"error_counters"`
+}
+
+func (s *errorCountersCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	output, err := connection.Cli(ctx, SHOW_ERRORS)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve error counters: %w", err)
+	}
+
+	counts := parseErrors(output)
+
+	// Counters accumulated before the agent started (or reconnected) are not reported as an increase, the first
+	// execution only records them
+	if s.previous == nil {
+		s.previous = counts
+		log.WithField("counters", len(counts)).Debug("Error counters baseline recorded")
+		return nil
+	}
+
+	var changed []errorCounter
+	for key, count := range counts {
+		previous, isPresent := s.previous[key]
+		if isPresent && count == previous {
+			continue
+		}
+
+		delta := count
+		if isPresent && count > previous {
+			delta = count - previous
+		}
+		// Otherwise a counter that appeared after the baseline or went backwards (cleared or VPP restarted),
+		// counting from 0
+		changed = append(changed, errorCounter{key.node, key.reason, count, delta})
+	}
+	// VPP lists only non-zero counters, counters missing now were cleared and start from 0 if they show up again
+	s.previous = counts
+
+	if len(changed) == 0 {
+		log.Debug("No error counter changed")
+		return nil
+	}
+
+	sort.Slice(changed, func(i, j int) bool {
+		if changed[i].Node != changed[j].Node {
+			return changed[i].Node < changed[j].Node
+		}
+		return changed[i].Reason < changed[j].Reason
+	})
+	stat := errorCounters{Counters: changed}
+
+	log.WithFields(log.Fields{
+		"error-counters": util.StringOf(stat),
+	}).Debug("Error counters polled successfully")
+
+	s.aggregator.Channel() <- stat
+	return nil
+}
+
+// Parse show errors output, e.g.:
+//
+//	Count                    Node                  Reason
+//	      5             ip4-input               ip4 ttl <= 1
+//	     12           ethernet-input            l3 mac mismatch
+//
+// With worker threads, VPP lists counters per thread (sections starting with "Thread 0 (vpp_main):") followed by
+// a "Total:" section. Totals are used if present, counters of all threads are summed up otherwise.
+func parseErrors(output string) map[errorKey]uint64 {
+	summed := make(map[errorKey]uint64)
+	var total map[errorKey]uint64
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "Total:" {
+			total = make(map[errorKey]uint64)
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		count, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			// Header or thread section
+			continue
+		}
+
+		key := errorKey{fields[1], strings.Join(fields[2:], " ")}
+		if total != nil {
+			total[key] += count
+		} else {
+			summed[key] += count
+		}
+	}
+
+	if total != nil {
+		return total
+	}
+	return summed
+}
+
+// VPP API messages the collector depends on
+func (s *errorCountersCollector) Messages() []api.Message {
+	return []api.Message{&api.CliInband{}, &api.CliInbandReply{}}
+}
+
+func (s *errorCountersCollector) Close() {
+	s.aggregator = nil
+	s.configuration = ErrorCountersCollectorConfiguration{}
+}
@@ -0,0 +1,103 @@
+package error_counters
+
+import (
+	"context"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const header = "   Count                    Node                  Reason\n"
+
+func TestCollectChangedErrorCounters(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := ErrorCountersCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	executions := []struct {
+		output   string
+		expected []errorCounter
+	}{
+		{
+			// Baseline, counters accumulated before the first execution are not reported
+			output: header +
+				"         5             ip4-input               ip4 ttl <= 1\n" +
+				"        12           ethernet-input            l3 mac mismatch\n",
+		},
+		{
+			// Only the changed counter is reported
+			output: header +
+				"         8             ip4-input               ip4 ttl <= 1\n" +
+				"        12           ethernet-input            l3 mac mismatch\n",
+			expected: []errorCounter{
+				{Node: "ip4-input", Reason: "ip4 ttl <= 1", Count: 8, Delta: 3},
+			},
+		},
+		{
+			// Nothing changed
+			output: header +
+				"         8             ip4-input               ip4 ttl <= 1\n" +
+				"        12           ethernet-input            l3 mac mismatch\n",
+		},
+		{
+			// A counter appearing after the baseline counts from 0
+			output: header +
+				"         8             ip4-input               ip4 ttl <= 1\n" +
+				"        12           ethernet-input            l3 mac mismatch\n" +
+				"         3             ip6-input               ip6 ttl <= 1\n",
+			expected: []errorCounter{
+				{Node: "ip6-input", Reason: "ip6 ttl <= 1", Count: 3, Delta: 3},
+			},
+		},
+		{
+			// Counters cleared, the reset counter counts from 0
+			output: header +
+				"         2             ip4-input               ip4 ttl <= 1\n",
+			expected: []errorCounter{
+				{Node: "ip4-input", Reason: "ip4 ttl <= 1", Count: 2, Delta: 2},
+			},
+		},
+		{
+			// Per thread counters are summed up in the total
+			output: "Thread 0 (vpp_main):\n" + header +
+				"         2             ip4-input               ip4 ttl <= 1\n" +
+				"Thread 1 (vpp_wk_0):\n" + header +
+				"         4             ip4-input               ip4 ttl <= 1\n" +
+				"Total:\n" + header +
+				"         6             ip4-input               ip4 ttl <= 1\n",
+			expected: []errorCounter{
+				{Node: "ip4-input", Reason: "ip4 ttl <= 1", Count: 6, Delta: 4},
+			},
+		},
+	}
+
+	for i, execution := range executions {
+		server.SetCliOutput(SHOW_ERRORS, execution.output)
+		if err := clctr.Collect(context.Background(), connection); err != nil {
+			t.Fatalf("Collection %d failed: %v", i, err)
+		}
+
+		if execution.expected == nil {
+			// Stats are published before Collect returns
+			select {
+			case stat := <-aggr.Ch:
+				t.Errorf("Execution %d: received unexpected error counters: %v", i, stat)
+			default:
+			}
+			continue
+		}
+
+		select {
+		case stat := <-aggr.Ch:
+			expected := errorCounters{Counters: execution.expected}
+			if !reflect.DeepEqual(stat, expected) {
+				t.Errorf("Execution %d: received invalid error counters, expected: %v, received: %v", i, expected, stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatalf("Execution %d: timed out. Did not receive error counters", i)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package fib_counters
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"net"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/util"
+)
+
+type FibCountersCollectorConfiguration struct {
+	Name string
+}
+
+func (s FibCountersCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator) collector.Collector {
+	clctr := &fibCountersCollector{
+		configuration: s,
+		aggregator:    aggregator,
+	}
+
+	log.WithFields(log.Fields{
+		"collector": clctr,
+	}).Debug("FibCountersCollector created successfully")
+
+	return clctr
+}
+
+type fibCountersCollector struct {
+	configuration   FibCountersCollectorConfiguration
+	aggregator      aggregator.CollectorAggregator
+	ip4Subscription *govpp.Subscription
+	ip6Subscription *govpp.Subscription
+}
+
+// Counters of traffic matching a single prefix
+type fibCounter struct {
+	Prefix      string `json:"prefix"`
+	PacketCount uint64 `json:"packet_count"`
+	ByteCount   uint64 `json:"byte_count"`
+}
+
+type ip4FibCounters struct {
+	VrfId    uint         `json:"vrf_id"`
+	Counters []fibCounter `json:"ipv4_fib_counters"`
+}
+
+type ip6FibCounters struct {
+	VrfId    uint         `json:"vrf_id"`
+	Counters []fibCounter `json:"ipv6_fib_counters"`
+}
+
+func (s *fibCountersCollector) ip4FibCountersCallback(msg api.Message, _ uint) {
+	counters := msg.(*api.VnetIp4FibCounters)
+
+	var result []fibCounter
+	for _, ctr := range counters.Counters {
+		if ctr.Packets == 0 && ctr.Bytes == 0 {
+			// Skip 0-ed counters
+			continue
+		}
+		result = append(result, fibCounter{prefix(ctr.Address, ctr.AddressLength), ctr.Packets, ctr.Bytes})
+	}
+
+	s.publish(ip4FibCounters{uint(counters.VrfID), result}, len(result))
+}
+
+func (s *fibCountersCollector) ip6FibCountersCallback(msg api.Message, _ uint) {
+	counters := msg.(*api.VnetIp6FibCounters)
+
+	var result []fibCounter
+	for _, ctr := range counters.Counters {
+		if ctr.Packets == 0 && ctr.Bytes == 0 {
+			// Skip 0-ed counters
+			continue
+		}
+		result = append(result, fibCounter{prefix(ctr.Address, ctr.AddressLength), ctr.Packets, ctr.Bytes})
+	}
+
+	s.publish(ip6FibCounters{uint(counters.VrfID), result}, len(result))
+}
+
+func (s *fibCountersCollector) publish(stat aggregator.Stat, count int) {
+	if count == 0 {
+		// Skip empty counters
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"fib-counter": util.StringOf(stat),
+	}).Debug("Received fib counter notifications")
+	s.aggregator.Channel() <- stat
+}
+
+// Prefix in CIDR notation
+func prefix(address []byte, length uint8) string {
+	return fmt.Sprintf("%v/%d", net.IP(address), length)
+}
+
+func (s *fibCountersCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	if s.ip4Subscription == nil {
+		s.ip4Subscription = connection.Subscribe(&api.VnetIp4FibCounters{}, s.ip4FibCountersCallback)
+		s.ip6Subscription = connection.Subscribe(&api.VnetIp6FibCounters{}, s.ip6FibCountersCallback)
+	}
+
+	// Same subscription as for interface counters, VPP sends all its counters to clients wanting stats
+	request := &api.WantStats{EnableDisable: 1, Pid: uint32(connection.GetPid())}
+	if _, err := connection.SendRequest(ctx, request); err != nil {
+		return fmt.Errorf("Unable to activate fib counter notifications: %w", err)
+	}
+
+	log.Debug("Successfully activated fib counter notifications")
+	return nil
+}
+
+// VPP API messages the collector depends on
+func (s *fibCountersCollector) Messages() []api.Message {
+	return []api.Message{&api.WantStats{}, &api.WantStatsReply{}, &api.VnetIp4FibCounters{}, &api.VnetIp6FibCounters{}}
+}
+
+func (s *fibCountersCollector) Close() {
+	if s.ip4Subscription != nil {
+		s.ip4Subscription.Unsubscribe()
+		s.ip6Subscription.Unsubscribe()
+		s.ip4Subscription = nil
+		s.ip6Subscription = nil
+	}
+	s.aggregator = nil
+	s.configuration = FibCountersCollectorConfiguration{}
+}
@@ -0,0 +1,99 @@
+package fib_counters
+
+import (
+	"context"
+	"net"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFibCounters(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := FibCountersCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantStats{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectCounters(&api.VnetIp4FibCounters{VrfID: 1, Count: 2, Counters: []api.Ip4FibCounter{
+		{Address: net.ParseIP("10.0.0.0").To4(), AddressLength: 24, Packets: 10, Bytes: 1000},
+		{Address: net.ParseIP("192.168.1.1").To4(), AddressLength: 32},
+	}})
+	server.InjectCounters(&api.VnetIp6FibCounters{VrfID: 0, Count: 1, Counters: []api.Ip6FibCounter{
+		{Address: net.ParseIP("2001:db8::"), AddressLength: 64, Packets: 20, Bytes: 2000},
+	}})
+
+	expected := []aggregator.Stat{
+		ip4FibCounters{VrfId: 1, Counters: []fibCounter{{Prefix: "10.0.0.0/24", PacketCount: 10, ByteCount: 1000}}},
+		ip6FibCounters{VrfId: 0, Counters: []fibCounter{{Prefix: "2001:db8::/64", PacketCount: 20, ByteCount: 2000}}},
+	}
+
+	for _, expectedStat := range expected {
+		select {
+		case stat := <-aggr.Ch:
+			if !reflect.DeepEqual(stat, expectedStat) {
+				t.Errorf("Received invalid counters, expected: %v, received: %v", expectedStat, stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive counters")
+		}
+	}
+}
+
+// Callbacks may still be running on the connection when the collector is closed, e.g. on reconnect
+func TestCloseWhileReceivingCounters(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+
+	aggr := vpptest.NewChannelAggregator(0)
+	clctr := FibCountersCollectorConfiguration{Name: "Test"}.Create(aggr)
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantStats{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	counters := &api.VnetIp4FibCounters{VrfID: 1, Count: 1, Counters: []api.Ip4FibCounter{
+		{Address: net.ParseIP("10.0.0.0").To4(), AddressLength: 24, Packets: 10, Bytes: 1000},
+	}}
+	for i := 0; i < 10; i++ {
+		server.InjectCounters(counters)
+	}
+
+	// Receive the first stat, the callback for the next one blocks on the aggregator while closing
+	select {
+	case <-aggr.Ch:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Did not receive counters")
+	}
+	closed := make(chan (int))
+	go func() {
+		clctr.Close()
+		close(closed)
+	}()
+	for {
+		select {
+		case <-aggr.Ch:
+			continue
+		case <-closed:
+		}
+		break
+	}
+
+	// Connection keeps dispatching messages after the collector was closed
+	server.InjectCounters(counters)
+	if _, err := connection.SendRequest(context.Background(), &api.ShowVersion{}); err != nil {
+		t.Errorf("Connection unusable after closing the collector: %v", err)
+	}
+}
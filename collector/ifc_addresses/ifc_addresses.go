@@ -0,0 +1,123 @@
+package ifc_addresses
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"net"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/collector/ifc_registry"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/util"
+)
+
+type InterfaceAddressesCollectorConfiguration struct {
+	Name string
+}
+
+func (s InterfaceAddressesCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator) collector.Collector {
+	clctr := interfaceAddressesCollector{
+		configuration: s,
+		aggregator:    aggregator,
+	}
+
+	log.WithFields(log.Fields{
+		"collector": clctr,
+	}).Debug("InterfaceAddressesCollector created successfully")
+
+	return clctr
+}
+
+type interfaceAddressesCollector struct {
+	configuration InterfaceAddressesCollectorConfiguration
+	aggregator    aggregator.CollectorAggregator
+}
+
+// Addresses of a single interface, in CIDR notation
+type interfaceAddresses struct {
+	InterfaceIndex uint `json:"interface_index"`
+	ifc_registry.Interface
+	Ipv4Addresses []string `json:"ipv4_addresses"`
+	Ipv6Addresses []string `json:"ipv6_addresses"`
+}
+
+// Address inventory of all interfaces
+type addressInventory struct {
+	Interfaces []interfaceAddresses `json:"interface_addresses"`
+}
+
+// VPP API messages the collector depends on
+func (s interfaceAddressesCollector) Messages() []api.Message {
+	return []api.Message{&api.SwInterfaceDump{}, &api.SwInterfaceDetails{}, &api.IpAddressDump{},
+		&api.IpAddressDetails{}, &api.ControlPing{}, &api.ControlPingReply{}}
+}
+
+func (s interfaceAddressesCollector) Close() {
+}
+
+func (s interfaceAddressesCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	allDetails, err := ifc_registry.DumpInterfaces(ctx, connection)
+	if err != nil {
+		return err
+	}
+	ifc_registry.For(connection).Update(allDetails)
+
+	var inventory addressInventory
+	for _, details := range allDetails {
+		addresses := interfaceAddresses{
+			InterfaceIndex: uint(details.SwIfIndex),
+			Interface:      ifc_registry.InterfaceOf(details),
+		}
+
+		if addresses.Ipv4Addresses, err = dumpAddresses(ctx, connection, details.SwIfIndex, false); err != nil {
+			return err
+		}
+		if addresses.Ipv6Addresses, err = dumpAddresses(ctx, connection, details.SwIfIndex, true); err != nil {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"interface-addresses": util.StringOf(addresses),
+		}).Debug("Received interface addresses")
+
+		inventory.Interfaces = append(inventory.Interfaces, addresses)
+	}
+
+	log.WithFields(log.Fields{
+		"address-inventory": util.StringOf(inventory),
+	}).Debug("Aggregated interface addresses")
+
+	s.aggregator.Channel() <- inventory
+	return nil
+}
+
+// Addresses of a single interface and IP version in CIDR notation, never nil so that an interface without
+// addresses is reported with an empty list
+func dumpAddresses(ctx context.Context, connection *govpp.VppConnection, ifcIndex uint32, ipv6 bool) ([]string, error) {
+	request := &api.IpAddressDump{SwIfIndex: ifcIndex}
+	addressLength := net.IPv4len
+	if ipv6 {
+		request.IsIpv6 = 1
+		addressLength = net.IPv6len
+	}
+
+	replies := connection.SendMultiRequest(ctx, request)
+	defer replies.Close()
+
+	addresses := []string{}
+	for {
+		reply, err := replies.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to dump addresses of interface %d: %w", ifcIndex, err)
+		}
+		if reply == nil {
+			return addresses, nil
+		}
+
+		details := reply.(*api.IpAddressDetails)
+		ip := net.IP(details.Ip[:addressLength])
+		addresses = append(addresses, fmt.Sprintf("%v/%d", ip, details.PrefixLength))
+	}
+}
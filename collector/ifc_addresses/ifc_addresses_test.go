@@ -0,0 +1,57 @@
+package ifc_addresses
+
+import (
+	"context"
+	"net"
+	"pnda/vpp/monitoring/collector/ifc_registry"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollectAddresses(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	server.SetInterfaces(
+		api.SwInterfaceDetails{SwIfIndex: 0, InterfaceName: "local0"},
+		api.SwInterfaceDetails{SwIfIndex: 1, InterfaceName: "GigabitEthernet0/8/0"})
+	server.SetIpAddresses(
+		api.IpAddressDetails{SwIfIndex: 1, Ip: net.ParseIP("10.0.0.1").To4(), PrefixLength: 24},
+		api.IpAddressDetails{SwIfIndex: 1, Ip: net.ParseIP("192.168.1.1").To4(), PrefixLength: 32},
+		api.IpAddressDetails{SwIfIndex: 1, Ip: net.ParseIP("2001:db8::1"), PrefixLength: 64, IsIpv6: 1})
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceAddressesCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+
+	expected := addressInventory{Interfaces: []interfaceAddresses{
+		{
+			InterfaceIndex: 0,
+			Interface:      ifc_registry.Interface{InterfaceName: "local0"},
+			Ipv4Addresses:  []string{},
+			Ipv6Addresses:  []string{},
+		},
+		{
+			InterfaceIndex: 1,
+			Interface:      ifc_registry.Interface{InterfaceName: "GigabitEthernet0/8/0"},
+			Ipv4Addresses:  []string{"10.0.0.1/24", "192.168.1.1/32"},
+			Ipv6Addresses:  []string{"2001:db8::1/64"},
+		},
+	}}
+
+	select {
+	case stat := <-aggr.Ch:
+		if !reflect.DeepEqual(stat, expected) {
+			t.Errorf("Received invalid addresses, expected: %v, received: %v", expected, stat)
+		}
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Did not receive addresses")
+	}
+}
@@ -1,122 +1,79 @@
 package ifc_counters
 
-/*
-#cgo LDFLAGS: -L/usr/lib/x86_64-linux-gnu -lvlibmemoryclient -lvlibapi -lsvm -lvppinfra -lpthread -lm -lrt -lpneum
-
-#include <vnet/vnet.h>
-#include <vlib/vlib.h>
-#include <vlib/unix/unix.h>
-#include <vlibapi/api.h>
-#include <vlibmemory/api.h>
-
-#include <vpp/api/vpe_msg_enum.h>
-
-#define vl_typedefs
-#include <vpp/api/vpe_all_api_h.h>
-#undef vl_typedefs
-
-#define vl_endianfun
-#include <vpp/api/vpe_all_api_h.h>
-#undef vl_endianfun
-
-// Callback cannot be in Go, since it's impossible to send pointer to a Go func to C
-void wantStatsCallback(u32 retval);
-static inline void vl_api_want_stats_reply_t_handler(vl_api_want_stats_reply_t * mp) {
-	wantStatsCallback(clib_net_to_host_u32(mp->retval));
-}
-
-void ifcCounterCallback(u8 type, u32 ifc_index, u32 count, u8 * data);
-void ifcCombinedCounterCallback(u8 type, u32 ifc_index, u32 count, u8 * data);
-static inline void vl_api_vnet_interface_counters_t_handler(vl_api_vnet_interface_counters_t * mp) {
-
-	if (mp->is_combined == 0) {
-		ifcCounterCallback(mp->vnet_counter_type, clib_net_to_host_u32(mp->first_sw_if_index),
-			clib_net_to_host_u32(mp->count), mp->data);
-	} else {
-		ifcCombinedCounterCallback(mp->vnet_counter_type, clib_net_to_host_u32(mp->first_sw_if_index),
-			clib_net_to_host_u32(mp->count), mp->data);
-	}
-}
-
-static inline void register_callback() {
-	vl_msg_api_set_handlers(VL_API_WANT_INTERFACE_EVENTS_REPLY, "want_stats_reply",
-	vl_api_want_stats_reply_t_handler,
-	vl_noop_handler, vl_noop_handler, vl_noop_handler,
-	sizeof(vl_api_want_stats_reply_t), 1);
-
-	vl_msg_api_set_handlers(VL_API_VNET_INTERFACE_COUNTERS, "vnet_interface_counters",
-	vl_api_vnet_interface_counters_t_handler,
-	vl_noop_handler, vl_noop_handler, vl_noop_handler,
-	sizeof(vl_api_vnet_interface_counters_t), 1);
-
-	// FIXME handle ipv4_fib_counters
-	//vl_msg_api_set_handlers(VL_API_VNET_IP4_FIB_COUNTERS, "vnet_ip4_fib_counters",
-	//vl_api_vnet_ip4_fib_counters_t_handler,
-	//vl_noop_handler, vl_noop_handler, vl_noop_handler,
-	//sizeof(vl_api_vnet_ip4_fib_counters_t), 1);
-}
-
-static inline vl_api_want_stats_t* new_request(u32 client_id, u32 enable) {
-	vl_api_want_stats_t * mp;
-	mp = vl_msg_api_alloc(sizeof(*mp));
-	memset (mp, 0, sizeof (*mp));
-
-	mp->_vl_msg_id = ntohs (VL_API_WANT_STATS);
-	mp->client_index = client_id;
-	mp->enable_disable = enable;
-	return mp;
-}
-*/
-import "C"
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"pnda/vpp/monitoring/aggregator"
 	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/collector/ifc_registry"
 	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
 	"pnda/vpp/monitoring/util"
-	"unsafe"
+	"sync"
+	"time"
 )
 
 type InterfaceCountersCollectorConfiguration struct {
 	Name string
+	// Report packets/s (and bits/s for combined counters) computed from consecutive samples next to the totals
+	Rates bool
+	// Report a single interfaceStatistics record per interface and sample instead of a batch per counter type
+	Consolidated bool
+	// Report interfaces with zero counters as well
+	IncludeZeroCounters bool
 }
 
-var singletonCollector *interfaceCountersCollector = nil
-
 func (s InterfaceCountersCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator) collector.Collector {
-	if singletonCollector != nil {
-		log.WithFields(log.Fields{
-			"collector": singletonCollector,
-		}).Panic("Collector(singleton) already exists")
-	}
-
-	// FIXME handle ipv4_fib_counters
-	C.register_callback()
-
-	singletonCollector = &interfaceCountersCollector{
+	clctr := &interfaceCountersCollector{
 		configuration: s,
 		aggregator:    aggregator,
+		samples:       make(map[sampleKey]sample),
 	}
 
 	log.WithFields(log.Fields{
-		"collector": singletonCollector,
+		"collector": clctr,
 	}).Debug("InterfaceCountersCollector created successfully")
 
-	return singletonCollector
+	return clctr
 }
 
 type interfaceCountersCollector struct {
-	configuration InterfaceCountersCollectorConfiguration
-	aggregator    aggregator.CollectorAggregator
+	configuration        InterfaceCountersCollectorConfiguration
+	aggregator           aggregator.CollectorAggregator
+	simpleSubscription   *govpp.Subscription
+	combinedSubscription *govpp.Subscription
+	registry             *ifc_registry.Registry
+	samplesLock          sync.Mutex
+	samples              map[sampleKey]sample
+	// Consolidated mode only, statistics of the sample being received
+	pendingLock  sync.Mutex
+	pending      map[uint]*interfaceStatistics
+	pendingTypes map[counterKey]bool
+	flushTimer   *time.Timer
+	sampleId     uint
+}
+
+// Identification of a single counter of an interface
+type sampleKey struct {
+	combined    bool
+	counterType uint8
+	ifcIndex    uint
+}
+
+// Previous value of a counter, used to compute rates
+type sample struct {
+	packets uint64
+	bytes   uint64
+	time    time.Time
 }
 
 type interfaceCounter interface{}
 
 // Counters
 
+// Simple counter types, in the order of vnet_interface_counter_type_t in VPP. SIMPLE_INTERFACE_COUNTER is their
+// number, not a type.
 type CounterType byte
 
 const (
@@ -133,14 +90,21 @@ const (
 )
 
 type counter struct {
-	InterfaceIndex uint   `json:"interface_index"`
-	PacketCount    uint64 `json:"packet_count"`
+	InterfaceIndex uint `json:"interface_index"`
+	ifc_registry.Interface
+	PacketCount uint64 `json:"packet_count"`
+	// Only in rate mode, once a previous sample is available
+	PacketsPerSecond *float64 `json:"packets_per_second,omitempty"`
 }
 
 type dropCounters struct {
 	Counters []interfaceCounter `json:"drop_counters"`
 }
 
+type puntCounters struct {
+	Counters []interfaceCounter `json:"punt_counters"`
+}
+
 type ip4Counters struct {
 	Counters []interfaceCounter `json:"ipv4_counters"`
 }
@@ -149,12 +113,36 @@ type ip6Counters struct {
 	Counters []interfaceCounter `json:"ipv6_counters"`
 }
 
+type rxNoBufCounters struct {
+	Counters []interfaceCounter `json:"rx_no_buf_counters"`
+}
+
+type rxMissCounters struct {
+	Counters []interfaceCounter `json:"rx_miss_counters"`
+}
+
+type rxErrorCounters struct {
+	Counters []interfaceCounter `json:"rx_error_counters"`
+}
+
+type txErrorCounters struct {
+	Counters []interfaceCounter `json:"tx_error_counters"`
+}
+
+type mplsCounters struct {
+	Counters []interfaceCounter `json:"mpls_counters"`
+}
+
 // Combined counters
 
 type combinedCounter struct {
-	InterfaceIndex uint   `json:"interface_index"`
-	PacketCount    uint64 `json:"packet_count"`
-	ByteCount      uint64 `json:"byte_count"`
+	InterfaceIndex uint `json:"interface_index"`
+	ifc_registry.Interface
+	PacketCount uint64 `json:"packet_count"`
+	ByteCount   uint64 `json:"byte_count"`
+	// Only in rate mode, once a previous sample is available
+	PacketsPerSecond *float64 `json:"packets_per_second,omitempty"`
+	BitsPerSecond    *float64 `json:"bits_per_second,omitempty"`
 }
 
 type rxCombinedCounters struct {
@@ -172,44 +160,62 @@ const (
 	TX
 )
 
-//export wantStatsCallback
-func wantStatsCallback(retval C.u32) {
-	if retval < 0 {
-		log.WithField("retval", retval).Panic("Interface counters activation failed")
-	}
+func (s *interfaceCountersCollector) simpleCountersCallback(msg api.Message, _ uint) {
+	counters := msg.(*api.VnetInterfaceSimpleCounters)
 
-	log.WithFields(log.Fields{
-		"retval": retval,
-	}).Debug("Successfully activated interface counter notifications")
+	decoded := make([]decodedCounter, len(counters.Data))
+	for n, packets := range counters.Data {
+		decoded[n] = decodedCounter{ifcIndex: uint(counters.FirstSwIfIndex) + uint(n), packets: packets}
+	}
+	s.countersReceived(false, counters.VnetCounterType, counters.FirstSwIfIndex, decoded)
 }
 
-//export ifcCounterCallback
-func ifcCounterCallback(counterType C.u8, ifcIndex C.u32, count C.u32, data *C.u8) {
-	var result []interfaceCounter
-	var ctrType CounterType
+func (s *interfaceCountersCollector) combinedCountersCallback(msg api.Message, _ uint) {
+	counters := msg.(*api.VnetInterfaceCombinedCounters)
 
-	// 8 == size of uint64, 2 == entry for packages + entry for bytes, count == number of interfaces
-	goData := C.GoBytes(unsafe.Pointer(data), C.int(8*2*count))
-	buf := bytes.NewReader(goData)
+	decoded := make([]decodedCounter, len(counters.Data))
+	for n, counter := range counters.Data {
+		decoded[n] = decodedCounter{ifcIndex: uint(counters.FirstSwIfIndex) + uint(n), packets: counter.Packets,
+			bytes: counter.Bytes}
+	}
+	s.countersReceived(true, counters.VnetCounterType, counters.FirstSwIfIndex, decoded)
+}
+
+func (s *interfaceCountersCollector) countersReceived(combined bool, counterType uint8, firstIfcIndex uint32,
+	decoded []decodedCounter) {
 
-	// DATA is in shape of:
-	// [ifc0-packetCount, ifc1-packetCount, 0, 0]
-	// in case of non-combined, the byte counters are always 0
-	// in case of combined, they are filled in as well
+	if s.configuration.Consolidated {
+		s.consolidate(combined, counterType, firstIfcIndex, decoded, time.Now())
+	} else if combined {
+		s.ifcCombinedCounterCallback(counterType, decoded)
+	} else {
+		s.ifcCounterCallback(counterType, decoded)
+	}
+}
 
-	for i := uint(ifcIndex); i < uint(count); i++ {
-		var pktsCounter uint64
+// Counter values of a single interface
+type decodedCounter struct {
+	ifcIndex uint
+	packets  uint64
+	bytes    uint64
+}
 
-		//binary.Read(buf, binary.BigEndian, &bytesCounter)
-		binary.Read(buf, binary.BigEndian, &pktsCounter)
+func (s *interfaceCountersCollector) ifcCounterCallback(counterType uint8, decoded []decodedCounter) {
+	var result []interfaceCounter
+	now := time.Now()
 
-		if pktsCounter == 0 {
-			// Skip 0-ed counters
+	for _, value := range decoded {
+		if value.packets == 0 && !s.configuration.IncludeZeroCounters {
 			continue
 		}
 
-		ctrType = CounterType(counterType)
-		result = append(result, counter{InterfaceIndex: i, PacketCount: pktsCounter})
+		ctr := counter{InterfaceIndex: value.ifcIndex, Interface: s.registry.Lookup(value.ifcIndex),
+			PacketCount: value.packets}
+		if s.configuration.Rates {
+			ctr.PacketsPerSecond, _ =
+				s.rates(sampleKey{false, counterType, value.ifcIndex}, sample{value.packets, 0, now})
+		}
+		result = append(result, ctr)
 	}
 
 	if len(result) == 0 {
@@ -217,55 +223,59 @@ func ifcCounterCallback(counterType C.u8, ifcIndex C.u32, count C.u32, data *C.u
 		return
 	}
 
-	if aggrCounter, err := wrapCounters(result, ctrType); err == nil {
-		log.WithFields(log.Fields{
-			"interface-counter": util.StringOf(aggrCounter),
-		}).Debug("Received ifc counter notifications")
-		singletonCollector.aggregator.Channel() <- aggrCounter
+	aggrCounter, err := wrapCounters(result, CounterType(counterType))
+	if err != nil {
+		log.WithField("error", err).Warn("Ignoring ifc counter notification")
+		return
 	}
+
+	log.WithFields(log.Fields{
+		"interface-counter": util.StringOf(aggrCounter),
+	}).Debug("Received ifc counter notifications")
+	s.aggregator.Channel() <- aggrCounter
 }
 
 func wrapCounters(result []interfaceCounter, ctrType CounterType) (aggregator.Stat, error) {
 	switch ctrType {
 	case DROP:
 		return dropCounters{Counters: result}, nil
+	case PUNT:
+		return puntCounters{Counters: result}, nil
 	case IP4:
 		return ip4Counters{Counters: result}, nil
 	case IP6:
 		return ip6Counters{Counters: result}, nil
+	case RX_NOBUF:
+		return rxNoBufCounters{Counters: result}, nil
+	case RX_MISS:
+		return rxMissCounters{Counters: result}, nil
+	case RX_ERROR:
+		return rxErrorCounters{Counters: result}, nil
+	case TX_ERROR:
+		return txErrorCounters{Counters: result}, nil
+	case MPLS:
+		return mplsCounters{Counters: result}, nil
 	default:
-		return nil, fmt.Errorf("Unsupported couter type: %v", ctrType)
+		return nil, fmt.Errorf("Unsupported counter type: %v", ctrType)
 	}
 }
 
-//export ifcCombinedCounterCallback
-func ifcCombinedCounterCallback(counterType C.u8, ifcIndex C.u32, count C.u32, data *C.u8) {
+func (s *interfaceCountersCollector) ifcCombinedCounterCallback(counterType uint8, decoded []decodedCounter) {
 	var result []interfaceCounter
-	var ctrType CombinedCounterType
-
-	// 8 == size of uint64, 2 == entry for packages + entry for bytes, count == number of interfaces
-	goData := C.GoBytes(unsafe.Pointer(data), C.int(8*2*count))
-	buf := bytes.NewReader(goData)
-
-	// DATA is in shape of:
-	// [ifc0-packetCount, ifc0-byteCount, ifc1-packetCount, ifc1-byteCount]
-	// in case of non-combined, the byte counters are always 0
-	// in case of combined, they are filled in as well
-
-	for i := uint(ifcIndex); i < uint(count); i++ {
-		var pktsCounter, bytesCounter uint64
+	now := time.Now()
 
-		binary.Read(buf, binary.BigEndian, &pktsCounter)
-		binary.Read(buf, binary.BigEndian, &bytesCounter)
-
-		if pktsCounter == 0 && bytesCounter == 0 {
-			// Skip 0-ed counters
+	for _, value := range decoded {
+		if value.packets == 0 && value.bytes == 0 && !s.configuration.IncludeZeroCounters {
 			continue
 		}
 
-		ctrType = CombinedCounterType(counterType)
-		result = append(result,
-			combinedCounter{InterfaceIndex: i, PacketCount: pktsCounter, ByteCount: bytesCounter})
+		ctr := combinedCounter{InterfaceIndex: value.ifcIndex, Interface: s.registry.Lookup(value.ifcIndex),
+			PacketCount: value.packets, ByteCount: value.bytes}
+		if s.configuration.Rates {
+			ctr.PacketsPerSecond, ctr.BitsPerSecond =
+				s.rates(sampleKey{true, counterType, value.ifcIndex}, sample{value.packets, value.bytes, now})
+		}
+		result = append(result, ctr)
 	}
 
 	if len(result) == 0 {
@@ -273,12 +283,16 @@ func ifcCombinedCounterCallback(counterType C.u8, ifcIndex C.u32, count C.u32, d
 		return
 	}
 
-	if aggrCounter, err := wrapCombinedCounters(result, ctrType); err == nil {
-		log.WithFields(log.Fields{
-			"interface-counter": util.StringOf(aggrCounter),
-		}).Debug("Received ifc combined counter notifications")
-		singletonCollector.aggregator.Channel() <- aggrCounter
+	aggrCounter, err := wrapCombinedCounters(result, CombinedCounterType(counterType))
+	if err != nil {
+		log.WithField("error", err).Warn("Ignoring ifc combined counter notification")
+		return
 	}
+
+	log.WithFields(log.Fields{
+		"interface-counter": util.StringOf(aggrCounter),
+	}).Debug("Received ifc combined counter notifications")
+	s.aggregator.Channel() <- aggrCounter
 }
 
 func wrapCombinedCounters(result []interfaceCounter, ctrType CombinedCounterType) (aggregator.Stat, error) {
@@ -288,22 +302,81 @@ func wrapCombinedCounters(result []interfaceCounter, ctrType CombinedCounterType
 	case TX:
 		return txCombinedCounters{result}, nil
 	default:
-		return nil, fmt.Errorf("Unsupported combined couter type: %v", ctrType)
+		return nil, fmt.Errorf("Unsupported combined counter type: %v", ctrType)
 	}
 }
 
-func (s interfaceCountersCollector) Collect(connection *govpp.VppConnection) {
-	C.register_callback()
+// Packets/s and bits/s since the previous sample of the same counter. Nil if there is no previous sample or
+// the counter went backwards, meaning it was cleared or VPP restarted.
+func (s *interfaceCountersCollector) rates(key sampleKey, current sample) (*float64, *float64) {
+	s.samplesLock.Lock()
+	defer s.samplesLock.Unlock()
 
-	connection.SendMessage(
-		unsafe.Pointer(
-			C.new_request(
-				C.u32(connection.ClientIndex),
-				C.clib_host_to_net_u32(C.u32(1)))))
+	previous, isPresent := s.samples[key]
+	s.samples[key] = current
+
+	if !isPresent || current.packets < previous.packets || current.bytes < previous.bytes {
+		return nil, nil
+	}
+	elapsed := current.time.Sub(previous.time).Seconds()
+	if elapsed <= 0 {
+		return nil, nil
+	}
+
+	pps := float64(current.packets-previous.packets) / elapsed
+	bps := float64(current.bytes-previous.bytes) * 8 / elapsed
+	return &pps, &bps
 }
 
-func (s interfaceCountersCollector) Close() {
+func (s *interfaceCountersCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	// Interfaces are reloaded on each Collect, since they might have changed if VPP restarted
+	if s.registry == nil {
+		s.registry = ifc_registry.For(connection)
+	}
+	if err := s.registry.Refresh(ctx); err != nil {
+		log.WithField("error", err).Warn("Unable to load interfaces, counters will not carry interface names")
+	}
+
+	if s.simpleSubscription == nil {
+		s.simpleSubscription = connection.Subscribe(&api.VnetInterfaceSimpleCounters{}, s.simpleCountersCallback)
+		s.combinedSubscription = connection.Subscribe(&api.VnetInterfaceCombinedCounters{}, s.combinedCountersCallback)
+	}
+
+	// Counters of a restarted VPP start from zero again, rates are computed only from its own samples
+	s.samplesLock.Lock()
+	s.samples = make(map[sampleKey]sample)
+	s.samplesLock.Unlock()
+
+	request := &api.WantStats{EnableDisable: 1, Pid: uint32(connection.GetPid())}
+	if _, err := connection.SendRequest(ctx, request); err != nil {
+		return fmt.Errorf("Unable to activate interface counter notifications: %w", err)
+	}
+
+	log.Debug("Successfully activated interface counter notifications")
+	return nil
+}
+
+// VPP API messages the collector depends on
+func (s *interfaceCountersCollector) Messages() []api.Message {
+	return []api.Message{&api.WantStats{}, &api.WantStatsReply{}, &api.VnetInterfaceSimpleCounters{},
+		&api.VnetInterfaceCombinedCounters{}}
+}
+
+func (s *interfaceCountersCollector) Close() {
+	if s.simpleSubscription != nil {
+		s.simpleSubscription.Unsubscribe()
+		s.combinedSubscription.Unsubscribe()
+		s.simpleSubscription = nil
+		s.combinedSubscription = nil
+	}
+
+	s.pendingLock.Lock()
+	defer s.pendingLock.Unlock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	s.pending = nil
 	s.aggregator = nil
 	s.configuration = InterfaceCountersCollectorConfiguration{}
-	singletonCollector = nil
 }
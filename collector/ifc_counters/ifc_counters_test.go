@@ -0,0 +1,328 @@
+package ifc_counters
+
+import (
+	"context"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector/ifc_registry"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInterfaceCounters(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceCountersCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantStats{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectSimpleCounters(uint8(DROP), 0, 5, 0)
+	server.InjectCombinedCounters(uint8(RX), 0, [2]uint64{10, 1000}, [2]uint64{20, 2000})
+
+	expected := []aggregator.Stat{
+		dropCounters{Counters: []interfaceCounter{counter{InterfaceIndex: 0, PacketCount: 5}}},
+		rxCombinedCounters{Counters: []interfaceCounter{
+			combinedCounter{InterfaceIndex: 0, PacketCount: 10, ByteCount: 1000},
+			combinedCounter{InterfaceIndex: 1, PacketCount: 20, ByteCount: 2000},
+		}},
+	}
+
+	for _, expectedStat := range expected {
+		select {
+		case stat := <-aggr.Ch:
+			if !reflect.DeepEqual(stat, expectedStat) {
+				t.Errorf("Received invalid counters, expected: %v, received: %v", expectedStat, stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive counters")
+		}
+	}
+}
+
+func TestInterfaceCounterRates(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceCountersCollectorConfiguration{Name: "Test", Rates: true}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantStats{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func() combinedCounter {
+		select {
+		case stat := <-aggr.Ch:
+			return stat.(rxCombinedCounters).Counters[0].(combinedCounter)
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive counters")
+		}
+		return combinedCounter{}
+	}
+
+	// No rates without a previous sample
+	server.InjectCombinedCounters(uint8(RX), 0, [2]uint64{10, 1000})
+	if ctr := receive(); ctr.PacketsPerSecond != nil || ctr.BitsPerSecond != nil {
+		t.Errorf("Received rates for the first sample: %v", ctr)
+	}
+
+	time.Sleep(time.Millisecond * time.Duration(50))
+	server.InjectCombinedCounters(uint8(RX), 0, [2]uint64{20, 2000})
+	ctr := receive()
+	if ctr.PacketsPerSecond == nil || ctr.BitsPerSecond == nil || *ctr.PacketsPerSecond <= 0 {
+		t.Fatalf("Received no rates for the second sample: %v", ctr)
+	}
+	// 10 packets, 1000 bytes in the same interval
+	if ratio := *ctr.BitsPerSecond / *ctr.PacketsPerSecond; ratio < 799 || ratio > 801 {
+		t.Errorf("Invalid bits/s to packets/s ratio, expected: 800, received: %v", ratio)
+	}
+
+	// Counters cleared, no rates until the next sample
+	server.InjectCombinedCounters(uint8(RX), 0, [2]uint64{5, 500})
+	if ctr := receive(); ctr.PacketsPerSecond != nil || ctr.BitsPerSecond != nil {
+		t.Errorf("Received rates after counter reset: %v", ctr)
+	}
+}
+
+func TestAllSimpleCounterTypes(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceCountersCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantStats{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	counters := []interfaceCounter{counter{InterfaceIndex: 0, PacketCount: 7}}
+	expected := map[CounterType]aggregator.Stat{
+		DROP:     dropCounters{counters},
+		PUNT:     puntCounters{counters},
+		IP4:      ip4Counters{counters},
+		IP6:      ip6Counters{counters},
+		RX_NOBUF: rxNoBufCounters{counters},
+		RX_MISS:  rxMissCounters{counters},
+		RX_ERROR: rxErrorCounters{counters},
+		TX_ERROR: txErrorCounters{counters},
+		MPLS:     mplsCounters{counters},
+	}
+
+	for ctrType := DROP; ctrType < SIMPLE_INTERFACE_COUNTER; ctrType++ {
+		server.InjectSimpleCounters(uint8(ctrType), 0, 7)
+
+		select {
+		case stat := <-aggr.Ch:
+			if !reflect.DeepEqual(stat, expected[ctrType]) {
+				t.Errorf("Received invalid counters, expected: %v, received: %v", expected[ctrType], stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatalf("Timed out. Did not receive counters of type %v", ctrType)
+		}
+	}
+}
+
+func TestCountersNotStartingAtFirstInterface(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceCountersCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantStats{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectSimpleCounters(uint8(DROP), 2, 5, 6)
+	server.InjectCombinedCounters(uint8(TX), 3, [2]uint64{10, 1000}, [2]uint64{20, 2000})
+
+	expected := []aggregator.Stat{
+		dropCounters{Counters: []interfaceCounter{
+			counter{InterfaceIndex: 2, PacketCount: 5},
+			counter{InterfaceIndex: 3, PacketCount: 6},
+		}},
+		txCombinedCounters{Counters: []interfaceCounter{
+			combinedCounter{InterfaceIndex: 3, PacketCount: 10, ByteCount: 1000},
+			combinedCounter{InterfaceIndex: 4, PacketCount: 20, ByteCount: 2000},
+		}},
+	}
+
+	for _, expectedStat := range expected {
+		select {
+		case stat := <-aggr.Ch:
+			if !reflect.DeepEqual(stat, expectedStat) {
+				t.Errorf("Received invalid counters, expected: %v, received: %v", expectedStat, stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive counters")
+		}
+	}
+}
+
+func TestConsolidatedStatistics(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	for _, includeZero := range []bool{false, true} {
+		aggr := vpptest.NewChannelAggregator(10)
+		clctr := InterfaceCountersCollectorConfiguration{
+			Name:                "Test",
+			Consolidated:        true,
+			IncludeZeroCounters: includeZero,
+		}.Create(aggr)
+
+		if err := clctr.Collect(context.Background(), connection); err != nil {
+			t.Fatalf("Collection failed: %v", err)
+		}
+
+		server.InjectSimpleCounters(uint8(DROP), 1, 5, 0)
+		server.InjectSimpleCounters(uint8(RX_MISS), 1, 3, 0)
+		server.InjectCombinedCounters(uint8(RX), 1, [2]uint64{10, 1000}, [2]uint64{0, 0})
+
+		expected := []aggregator.Stat{
+			interfaceStatistics{InterfaceIndex: 1, Rx: combinedStatistics{Packets: 10, Bytes: 1000}, Drops: 5, RxMiss: 3},
+		}
+		if includeZero {
+			expected = append(expected, interfaceStatistics{InterfaceIndex: 2})
+		}
+
+		for _, expectedStat := range expected {
+			select {
+			case stat := <-aggr.Ch:
+				if !reflect.DeepEqual(stat, expectedStat) {
+					t.Errorf("Received invalid statistics, expected: %v, received: %v", expectedStat, stat)
+				}
+			case <-time.After(time.Second * time.Duration(5)):
+				t.Fatal("Timed out. Did not receive statistics")
+			}
+		}
+		select {
+		case stat := <-aggr.Ch:
+			t.Errorf("Received unexpected statistics: %v", stat)
+		case <-time.After(SAMPLE_WINDOW * 2):
+		}
+
+		clctr.Close()
+	}
+}
+
+func TestConsolidatedStatisticsRates(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceCountersCollectorConfiguration{Name: "Test", Rates: true, Consolidated: true}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantStats{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	simpleTypes := []CounterType{DROP, PUNT, IP4, IP6, RX_NOBUF, RX_MISS, RX_ERROR, TX_ERROR, MPLS}
+	injectSample := func(value uint64) interfaceStatistics {
+		for _, ctrType := range simpleTypes {
+			server.InjectSimpleCounters(uint8(ctrType), 0, value)
+		}
+		server.InjectCombinedCounters(uint8(RX), 0, [2]uint64{value, value * 100})
+		server.InjectCombinedCounters(uint8(TX), 0, [2]uint64{value, value * 100})
+
+		select {
+		case stat := <-aggr.Ch:
+			return stat.(interfaceStatistics)
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive statistics")
+		}
+		return interfaceStatistics{}
+	}
+
+	// No rates without a previous sample
+	statistics := injectSample(10)
+	for _, ctrType := range simpleTypes {
+		if rate := *statistics.simpleCounterRate(ctrType); rate != nil {
+			t.Errorf("Received rate of counter type %v for the first sample: %v", ctrType, *rate)
+		}
+	}
+	if statistics.Rx.PacketsPerSecond != nil || statistics.Tx.PacketsPerSecond != nil {
+		t.Errorf("Received rx/tx rates for the first sample: %v", statistics)
+	}
+
+	time.Sleep(time.Millisecond * time.Duration(50))
+	statistics = injectSample(20)
+	for _, ctrType := range simpleTypes {
+		if rate := *statistics.simpleCounterRate(ctrType); rate == nil || *rate <= 0 {
+			t.Errorf("Received no rate of counter type %v for the second sample: %v", ctrType, statistics)
+		}
+	}
+	if statistics.Rx.PacketsPerSecond == nil || statistics.Tx.BitsPerSecond == nil {
+		t.Errorf("Received no rx/tx rates for the second sample: %v", statistics)
+	}
+}
+
+// VPP splits counters of a single type into batches of interfaces, all of them belong to the same sample
+func TestConsolidatedStatisticsInBatches(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceCountersCollectorConfiguration{Name: "Test", Consolidated: true}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantStats{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectSimpleCounters(uint8(DROP), 0, 1, 2)
+	server.InjectSimpleCounters(uint8(DROP), 2, 3)
+	server.InjectCombinedCounters(uint8(RX), 0, [2]uint64{10, 1000})
+	server.InjectCombinedCounters(uint8(RX), 1, [2]uint64{20, 2000}, [2]uint64{30, 3000})
+
+	expected := []aggregator.Stat{
+		interfaceStatistics{InterfaceIndex: 0, Rx: combinedStatistics{Packets: 10, Bytes: 1000}, Drops: 1},
+		interfaceStatistics{InterfaceIndex: 1, Rx: combinedStatistics{Packets: 20, Bytes: 2000}, Drops: 2},
+		interfaceStatistics{InterfaceIndex: 2, Rx: combinedStatistics{Packets: 30, Bytes: 3000}, Drops: 3},
+	}
+
+	for _, expectedStat := range expected {
+		select {
+		case stat := <-aggr.Ch:
+			if !reflect.DeepEqual(stat, expectedStat) {
+				t.Errorf("Received invalid statistics, expected: %v, received: %v", expectedStat, stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive statistics")
+		}
+	}
+	select {
+	case stat := <-aggr.Ch:
+		t.Errorf("Received unexpected statistics: %v", stat)
+	case <-time.After(SAMPLE_WINDOW * 2):
+	}
+}
@@ -0,0 +1,235 @@
+package ifc_counters
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector/ifc_registry"
+	"pnda/vpp/monitoring/util"
+	"sort"
+	"time"
+)
+
+// VPP sends each counter type in a separate notification, all of them within a short burst. Notifications received
+// within the window are consolidated into a single sample.
+const SAMPLE_WINDOW = time.Millisecond * 100
+
+// All counters of a single interface in a single sample
+type interfaceStatistics struct {
+	InterfaceIndex uint `json:"interface_index"`
+	ifc_registry.Interface
+	Rx       combinedStatistics `json:"rx"`
+	Tx       combinedStatistics `json:"tx"`
+	Drops    uint64             `json:"drops"`
+	Punts    uint64             `json:"punts"`
+	Ip4      uint64             `json:"ip4"`
+	Ip6      uint64             `json:"ip6"`
+	Mpls     uint64             `json:"mpls"`
+	RxNoBuf  uint64             `json:"rx_no_buf"`
+	RxMiss   uint64             `json:"rx_miss"`
+	RxErrors uint64             `json:"rx_errors"`
+	TxErrors uint64             `json:"tx_errors"`
+	// Packets/s of the simple counters, only in rate mode, once a previous sample is available
+	DropsPerSecond    *float64 `json:"drops_per_second,omitempty"`
+	PuntsPerSecond    *float64 `json:"punts_per_second,omitempty"`
+	Ip4PerSecond      *float64 `json:"ip4_per_second,omitempty"`
+	Ip6PerSecond      *float64 `json:"ip6_per_second,omitempty"`
+	MplsPerSecond     *float64 `json:"mpls_per_second,omitempty"`
+	RxNoBufPerSecond  *float64 `json:"rx_no_buf_per_second,omitempty"`
+	RxMissPerSecond   *float64 `json:"rx_miss_per_second,omitempty"`
+	RxErrorsPerSecond *float64 `json:"rx_errors_per_second,omitempty"`
+	TxErrorsPerSecond *float64 `json:"tx_errors_per_second,omitempty"`
+}
+
+type combinedStatistics struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+	// Only in rate mode, once a previous sample is available
+	PacketsPerSecond *float64 `json:"packets_per_second,omitempty"`
+	BitsPerSecond    *float64 `json:"bits_per_second,omitempty"`
+}
+
+// Identification of a counter notification. VPP sends each counter type in batches of interfaces, each starting
+// at its own first interface index.
+type counterKey struct {
+	combined      bool
+	counterType   uint8
+	firstIfcIndex uint32
+}
+
+// Field of the simple counter type, nil for unsupported types
+func (s *interfaceStatistics) simpleCounter(ctrType CounterType) *uint64 {
+	switch ctrType {
+	case DROP:
+		return &s.Drops
+	case PUNT:
+		return &s.Punts
+	case IP4:
+		return &s.Ip4
+	case IP6:
+		return &s.Ip6
+	case RX_NOBUF:
+		return &s.RxNoBuf
+	case RX_MISS:
+		return &s.RxMiss
+	case RX_ERROR:
+		return &s.RxErrors
+	case TX_ERROR:
+		return &s.TxErrors
+	case MPLS:
+		return &s.Mpls
+	default:
+		return nil
+	}
+}
+
+// Rate field of the simple counter type, nil for unsupported types
+func (s *interfaceStatistics) simpleCounterRate(ctrType CounterType) **float64 {
+	switch ctrType {
+	case DROP:
+		return &s.DropsPerSecond
+	case PUNT:
+		return &s.PuntsPerSecond
+	case IP4:
+		return &s.Ip4PerSecond
+	case IP6:
+		return &s.Ip6PerSecond
+	case RX_NOBUF:
+		return &s.RxNoBufPerSecond
+	case RX_MISS:
+		return &s.RxMissPerSecond
+	case RX_ERROR:
+		return &s.RxErrorsPerSecond
+	case TX_ERROR:
+		return &s.TxErrorsPerSecond
+	case MPLS:
+		return &s.MplsPerSecond
+	default:
+		return nil
+	}
+}
+
+// Field of the combined counter type, nil for unsupported types
+func (s *interfaceStatistics) combinedCounter(ctrType CombinedCounterType) *combinedStatistics {
+	switch ctrType {
+	case RX:
+		return &s.Rx
+	case TX:
+		return &s.Tx
+	default:
+		return nil
+	}
+}
+
+func (s *interfaceStatistics) isZero() bool {
+	return s.Rx.Packets == 0 && s.Rx.Bytes == 0 && s.Tx.Packets == 0 && s.Tx.Bytes == 0 &&
+		s.Drops == 0 && s.Punts == 0 && s.Ip4 == 0 && s.Ip6 == 0 && s.Mpls == 0 &&
+		s.RxNoBuf == 0 && s.RxMiss == 0 && s.RxErrors == 0 && s.TxErrors == 0
+}
+
+// Merge a batch of counters of a single type into the pending sample. The sample is published once the window
+// elapses or once the same batch (counter type and first interface) is received again.
+func (s *interfaceCountersCollector) consolidate(combined bool, counterType uint8, firstIfcIndex uint32,
+	decoded []decodedCounter, now time.Time) {
+
+	key := counterKey{combined, counterType, firstIfcIndex}
+	if combined && CombinedCounterType(counterType) > TX ||
+		!combined && CounterType(counterType) >= SIMPLE_INTERFACE_COUNTER {
+		log.WithField("counter-type", key).Warn("Ignoring ifc counter notification of unsupported type")
+		return
+	}
+
+	s.pendingLock.Lock()
+	var complete []interfaceStatistics
+	if s.pendingTypes[key] {
+		complete = s.takePendingLocked()
+	}
+
+	if s.pending == nil {
+		s.pending = make(map[uint]*interfaceStatistics)
+		s.pendingTypes = make(map[counterKey]bool)
+		s.sampleId++
+		sampleId := s.sampleId
+		s.flushTimer = time.AfterFunc(SAMPLE_WINDOW, func() { s.flush(sampleId) })
+	}
+	s.pendingTypes[key] = true
+
+	for _, value := range decoded {
+		statistics, isPresent := s.pending[value.ifcIndex]
+		if !isPresent {
+			statistics = &interfaceStatistics{InterfaceIndex: value.ifcIndex,
+				Interface: s.registry.Lookup(value.ifcIndex)}
+			s.pending[value.ifcIndex] = statistics
+		}
+
+		if combined {
+			ctr := statistics.combinedCounter(CombinedCounterType(counterType))
+			ctr.Packets, ctr.Bytes = value.packets, value.bytes
+			if s.configuration.Rates {
+				ctr.PacketsPerSecond, ctr.BitsPerSecond =
+					s.rates(sampleKey{true, counterType, value.ifcIndex}, sample{value.packets, value.bytes, now})
+			}
+		} else {
+			*statistics.simpleCounter(CounterType(counterType)) = value.packets
+			if s.configuration.Rates {
+				*statistics.simpleCounterRate(CounterType(counterType)), _ =
+					s.rates(sampleKey{false, counterType, value.ifcIndex}, sample{value.packets, 0, now})
+			}
+		}
+	}
+	aggr := s.aggregator
+	s.pendingLock.Unlock()
+
+	s.publish(aggr, complete)
+}
+
+// Publish the pending sample once its window elapsed, unless the sample was published already
+func (s *interfaceCountersCollector) flush(sampleId uint) {
+	s.pendingLock.Lock()
+	if sampleId != s.sampleId || s.pending == nil {
+		s.pendingLock.Unlock()
+		return
+	}
+	complete := s.takePendingLocked()
+	aggr := s.aggregator
+	s.pendingLock.Unlock()
+
+	s.publish(aggr, complete)
+}
+
+// Statistics of the pending sample ordered by interface index, zero-valued interfaces are left out unless
+// configured otherwise
+func (s *interfaceCountersCollector) takePendingLocked() []interfaceStatistics {
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+
+	var complete []interfaceStatistics
+	for _, statistics := range s.pending {
+		if statistics.isZero() && !s.configuration.IncludeZeroCounters {
+			continue
+		}
+		complete = append(complete, *statistics)
+	}
+	s.pending = nil
+	s.pendingTypes = nil
+
+	sort.Slice(complete, func(i, j int) bool {
+		return complete[i].InterfaceIndex < complete[j].InterfaceIndex
+	})
+	return complete
+}
+
+// Publish statistics of a sample, a record per interface. Nothing is published once the collector is closed.
+func (s *interfaceCountersCollector) publish(aggr aggregator.CollectorAggregator, complete []interfaceStatistics) {
+	if aggr == nil {
+		return
+	}
+
+	for _, statistics := range complete {
+		log.WithFields(log.Fields{
+			"interface-statistics": util.StringOf(statistics),
+		}).Debug("Consolidated ifc counters")
+		aggr.Channel() <- statistics
+	}
+}
@@ -1,95 +1,36 @@
 package ifc_info
 
-/*
-#cgo LDFLAGS: -L/usr/lib/x86_64-linux-gnu -lvlibmemoryclient -lvlibapi -lsvm -lvppinfra -lpthread -lm -lrt -lpneum
-
-#include <vnet/vnet.h>
-#include <vlib/vlib.h>
-#include <vlib/unix/unix.h>
-#include <vlibapi/api.h>
-#include <vlibmemory/api.h>
-
-#include <vpp/api/vpe_msg_enum.h>
-
-#define vl_typedefs
-#include <vpp/api/vpe_all_api_h.h>
-#undef vl_typedefs
-
-#define vl_endianfun
-#include <vpp/api/vpe_all_api_h.h>
-#undef vl_endianfun
-
-// Callback cannot be in Go, since it's impossible to send a Go func pointer to C
-void detailsCallback(u32 context, u32 index, char * name, u32 l2AddrLength, char * l2Address);
-static inline void vl_api_sw_interface_details_t_handler(vl_api_sw_interface_details_t * mp) {
-	detailsCallback(clib_net_to_host_u32(mp->context), clib_net_to_host_u32(mp->sw_if_index), mp->interface_name,
-			clib_net_to_host_u32(mp->l2_address_length), mp->l2_address);
-}
-
-static inline void register_callback() {
-	vl_msg_api_set_handlers(VL_API_SW_INTERFACE_DETAILS, "sw_interface_details",
-	vl_api_sw_interface_details_t_handler,
-	vl_noop_handler, vl_noop_handler, vl_noop_handler,
-	sizeof(vl_api_sw_interface_details_t), 1);
-}
-
-static inline vl_api_sw_interface_dump_t* new_request(u32 client_id, u32 context) {
-	vl_api_sw_interface_dump_t * mp;
-	mp = vl_msg_api_alloc(sizeof(*mp));
-	memset (mp, 0, sizeof (*mp));
-
-	mp->_vl_msg_id = ntohs (VL_API_SW_INTERFACE_DUMP);
-	mp->client_index = client_id;
-	mp->context = context;
-	return mp;
-}
-*/
-import "C"
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
-	"net"
 	"pnda/vpp/monitoring/aggregator"
 	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/collector/ifc_registry"
 	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
 	"pnda/vpp/monitoring/util"
-	"sync"
-	"unsafe"
+	"reflect"
+	"strings"
 )
 
 type InterfaceInfoCollectorConfiguration struct {
 	Name string
-	// TODO configure which fields to include in the report
+	// JSON names of networkInterface fields to include in the report, all fields are included if empty.
+	// interface_index is always included.
+	Fields []string
 }
 
-// Support for multiple instances
-var collectorInstancesLock sync.Mutex
-var collectorInstances = make(map[InterfaceInfoCollectorConfiguration](interfaceInfoCollector))
-var callbackRegistered = false
-
 func (s InterfaceInfoCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator) collector.Collector {
-	if !callbackRegistered {
-		C.register_callback()
-	}
-
-	if _, ok := collectorInstances[s]; ok {
-		log.WithFields(log.Fields{
-			"configuration": s,
-		}).Panic("Collector already exists with same configuration")
-	}
-
 	clctr := interfaceInfoCollector{
 		configuration: s,
-		mapOfChannels: make(map[uint](chan networkInterface)),
 		aggregator:    aggregator,
+		fields:        selectFields(s.Fields),
 	}
 
-	collectorInstancesLock.Lock()
-	collectorInstances[s] = clctr
-	collectorInstancesLock.Unlock()
-
 	log.WithFields(log.Fields{
-		"collector-instances": collectorInstances,
+		"collector": clctr,
 	}).Debug("InterfaceInfoCollector created successfully")
 
 	return clctr
@@ -97,98 +38,193 @@ func (s InterfaceInfoCollectorConfiguration) Create(aggregator aggregator.Collec
 
 type interfaceInfoCollector struct {
 	configuration InterfaceInfoCollectorConfiguration
-	// Support for concurrent execution of Collect
-	mapOfChannels map[uint](chan networkInterface)
 	aggregator    aggregator.CollectorAggregator
+	fields        map[string]bool
 }
 
 type networkInterface struct {
 	InterfaceName  string `json:"interface_name"`
 	InterfaceIndex uint   `json:"interface_index"`
 	L2Address      string `json:"l2_address"`
+	Tag            string `json:"tag"`
+	AdminUp        bool   `json:"admin_up"`
+	LinkUp         bool   `json:"link_up"`
+	// e.g. 10G, empty if unknown
+	LinkSpeed string `json:"link_speed"`
+	// half or full, empty if unknown
+	LinkDuplex string `json:"link_duplex"`
+	LinkMtu    uint   `json:"link_mtu"`
+	// Index of the parent interface, equal to InterfaceIndex for interfaces other than sub-interfaces
+	SupInterfaceIndex uint          `json:"sup_interface_index"`
+	SubInterface      *subInterface `json:"sub_interface,omitempty"`
+	// Fields included in JSON, all if nil
+	fields map[string]bool
 }
 
-type interfaces struct {
-	Interfaces []networkInterface `json:"interfaces"`
+// VLAN matching of a sub-interface
+type subInterface struct {
+	SubId          uint `json:"sub_id"`
+	Dot1ad         bool `json:"dot1ad"`
+	NumberOfTags   uint `json:"number_of_tags"`
+	OuterVlanId    uint `json:"outer_vlan_id"`
+	InnerVlanId    uint `json:"inner_vlan_id"`
+	ExactMatch     bool `json:"exact_match"`
+	Default        bool `json:"default"`
+	OuterVlanIdAny bool `json:"outer_vlan_id_any"`
+	InnerVlanIdAny bool `json:"inner_vlan_id_any"`
 }
 
-func (s interfaceInfoCollector) Close() {
-	if clctr, isPresent := collectorInstances[s.configuration]; isPresent {
-		for _, ch := range clctr.mapOfChannels {
-			close(ch)
-		}
+// Serialize only selected fields
+func (s networkInterface) MarshalJSON() ([]byte, error) {
+	// Same fields, without the custom serialization
+	type allFields networkInterface
+	data, err := json.Marshal(allFields(s))
+	if err != nil || s.fields == nil {
+		return data, err
 	}
-	delete(collectorInstances, s.configuration)
-}
-
-func (s interfaceInfoCollector) Collect(connection *govpp.VppConnection) {
-	ctxId := connection.NextContextId()
 
-	s.mapOfChannels[ctxId] = make(chan networkInterface)
-
-	go collectInfo(s.aggregator, s.mapOfChannels[ctxId])
-
-	connection.SendMessage(
-		unsafe.Pointer(
-			C.new_request(
-				C.u32(connection.ClientIndex),
-				C.clib_host_to_net_u32(C.u32(ctxId)))))
-
-	connection.Ping(connection.NextContextId(), func(pid uint, ctx uint) {
-		close(s.mapOfChannels[ctxId])
-		delete(s.mapOfChannels, ctxId)
-	})
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	for field := range values {
+		if !s.fields[field] {
+			delete(values, field)
+		}
+	}
+	return json.Marshal(values)
 }
 
-//export detailsCallback
-func detailsCallback(context C.u32, idx C.u32, name *C.char, l2AddrLength C.u32, l2Address *C.char) {
-	var l2Addr string
-	if uint(l2AddrLength) > 0 {
-		goData := C.GoBytes(unsafe.Pointer(l2Address), C.int(l2AddrLength))
-		l2Addr = net.HardwareAddr(goData).String()
+// Set of fields to serialize, nil if all fields are requested. Unknown fields are ignored.
+func selectFields(requested []string) map[string]bool {
+	if len(requested) == 0 {
+		return nil
 	}
 
-	info := networkInterface{C.GoString(name), uint(idx), l2Addr}
-
-	log.WithFields(log.Fields{
-		"interface-details": util.StringOf(info),
-	}).Debug("Received interface details")
+	known := make(map[string]bool)
+	ifcType := reflect.TypeOf(networkInterface{})
+	for i := 0; i < ifcType.NumField(); i++ {
+		if name := strings.Split(ifcType.Field(i).Tag.Get("json"), ",")[0]; name != "" {
+			known[name] = true
+		}
+	}
 
-	findChannelByContext(uint(context)) <- info
+	fields := map[string]bool{"interface_index": true}
+	for _, field := range requested {
+		if !known[field] {
+			log.WithFields(log.Fields{
+				"field":        field,
+				"known-fields": known,
+			}).Warn("Unknown interface info field, ignoring")
+			continue
+		}
+		fields[field] = true
+	}
+	return fields
 }
 
-func findChannelByContext(context uint) chan networkInterface {
-	collectorInstancesLock.Lock()
-	defer collectorInstancesLock.Unlock()
+type interfaces struct {
+	Interfaces []networkInterface `json:"interfaces"`
+}
 
-	for _, clctr := range collectorInstances {
-		for ctxId, channel := range clctr.mapOfChannels {
-			if ctxId == context {
-				return channel
-			}
-		}
-	}
+// VPP API messages the collector depends on
+func (s interfaceInfoCollector) Messages() []api.Message {
+	return []api.Message{&api.SwInterfaceDump{}, &api.SwInterfaceDetails{}, &api.ControlPing{}, &api.ControlPingReply{}}
+}
 
-	panic(fmt.Errorf("Unable to find channel under contextId: %v", context))
+func (s interfaceInfoCollector) Close() {
 }
 
-func collectInfo(aggregator aggregator.CollectorAggregator, ch chan networkInterface) {
-	var allInfos []networkInterface
+func (s interfaceInfoCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	replies := connection.SendMultiRequest(ctx, &api.SwInterfaceDump{})
+	defer replies.Close()
 
+	var allInfos []networkInterface
+	var allDetails []*api.SwInterfaceDetails
 	for {
-		info, ok := <-ch
-		if !ok {
-			// Channel closed
+		reply, err := replies.Next()
+		if err != nil {
+			return fmt.Errorf("Unable to dump interface details: %w", err)
+		}
+		if reply == nil {
 			break
 		}
+
+		details := reply.(*api.SwInterfaceDetails)
+		allDetails = append(allDetails, details)
+		info := toNetworkInterface(details)
+		info.fields = s.fields
+
+		log.WithFields(log.Fields{
+			"interface-details": util.StringOf(info),
+		}).Debug("Received interface details")
+
 		allInfos = append(allInfos, info)
 	}
 
+	ifc_registry.For(connection).Update(allDetails)
+
 	aggregatedInfos := interfaces{Interfaces: allInfos}
 
 	log.WithFields(log.Fields{
 		"aggregated-interface-details": util.StringOf(aggregatedInfos),
 	}).Debug("Aggregated interface details")
 
-	aggregator.Channel() <- aggregatedInfos
+	s.aggregator.Channel() <- aggregatedInfos
+	return nil
+}
+
+func toNetworkInterface(details *api.SwInterfaceDetails) networkInterface {
+	info := networkInterface{
+		InterfaceName:     details.InterfaceName,
+		InterfaceIndex:    uint(details.SwIfIndex),
+		L2Address:         ifc_registry.L2Address(details),
+		Tag:               details.Tag,
+		AdminUp:           details.AdminUpDown != 0,
+		LinkUp:            details.LinkUpDown != 0,
+		LinkSpeed:         linkSpeed(details.LinkSpeed),
+		LinkDuplex:        linkDuplex(details.LinkDuplex),
+		LinkMtu:           uint(details.LinkMtu),
+		SupInterfaceIndex: uint(details.SupSwIfIndex),
+	}
+
+	if details.SupSwIfIndex != details.SwIfIndex {
+		info.SubInterface = &subInterface{
+			SubId:          uint(details.SubID),
+			Dot1ad:         details.SubDot1ad != 0,
+			NumberOfTags:   uint(details.SubNumberOfTags),
+			OuterVlanId:    uint(details.SubOuterVlanID),
+			InnerVlanId:    uint(details.SubInnerVlanID),
+			ExactMatch:     details.SubExactMatch != 0,
+			Default:        details.SubDefault != 0,
+			OuterVlanIdAny: details.SubOuterVlanIDAny != 0,
+			InnerVlanIdAny: details.SubInnerVlanIDAny != 0,
+		}
+	}
+	return info
+}
+
+// Link speeds as reported by VPP (a single bit set)
+var linkSpeeds = map[uint8]string{
+	1:  "10M",
+	2:  "100M",
+	4:  "1G",
+	8:  "10G",
+	16: "40G",
+	32: "100G",
+}
+
+func linkSpeed(speed uint8) string {
+	return linkSpeeds[speed]
+}
+
+func linkDuplex(duplex uint8) string {
+	switch duplex {
+	case 1:
+		return "half"
+	case 2:
+		return "full"
+	default:
+		return ""
+	}
 }
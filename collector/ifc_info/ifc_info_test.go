@@ -0,0 +1,115 @@
+package ifc_info
+
+import (
+	"context"
+	"pnda/vpp/monitoring/collector/ifc_registry"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"pnda/vpp/monitoring/util"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollectInterfaces(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+	server.SetInterfaces(
+		api.SwInterfaceDetails{SwIfIndex: 0, InterfaceName: "local0"},
+		api.SwInterfaceDetails{
+			SwIfIndex:       1,
+			SupSwIfIndex:    1,
+			InterfaceName:   "GigabitEthernet0/8/0",
+			L2AddressLength: 6,
+			L2Address:       []byte{0x08, 0x00, 0x27, 0x1a, 0x2b, 0x3c, 0, 0},
+			AdminUpDown:     1,
+			LinkUpDown:      1,
+			LinkSpeed:       8,
+			LinkDuplex:      2,
+			LinkMtu:         9216,
+		},
+		api.SwInterfaceDetails{
+			SwIfIndex:       2,
+			SupSwIfIndex:    1,
+			InterfaceName:   "GigabitEthernet0/8/0.100",
+			SubID:           100,
+			SubNumberOfTags: 1,
+			SubOuterVlanID:  100,
+			SubExactMatch:   1,
+		})
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := InterfaceInfoCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+
+	select {
+	case stat := <-aggr.Ch:
+		expected := interfaces{Interfaces: []networkInterface{
+			{InterfaceName: "local0", InterfaceIndex: 0},
+			{
+				InterfaceName:     "GigabitEthernet0/8/0",
+				InterfaceIndex:    1,
+				L2Address:         "08:00:27:1a:2b:3c",
+				AdminUp:           true,
+				LinkUp:            true,
+				LinkSpeed:         "10G",
+				LinkDuplex:        "full",
+				LinkMtu:           9216,
+				SupInterfaceIndex: 1,
+			},
+			{
+				InterfaceName:     "GigabitEthernet0/8/0.100",
+				InterfaceIndex:    2,
+				SupInterfaceIndex: 1,
+				SubInterface: &subInterface{
+					SubId:        100,
+					NumberOfTags: 1,
+					OuterVlanId:  100,
+					ExactMatch:   true,
+				},
+			},
+		}}
+		if !reflect.DeepEqual(stat, expected) {
+			t.Errorf("Received invalid interfaces, expected: %v, received: %v", expected, stat)
+		}
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Error("Timed out. Did not receive interfaces")
+	}
+}
+
+func TestSelectedFields(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+	server.SetInterfaces(api.SwInterfaceDetails{
+		SwIfIndex:     1,
+		SupSwIfIndex:  1,
+		InterfaceName: "GigabitEthernet0/8/0",
+		LinkUpDown:    1,
+		LinkMtu:       1500,
+	})
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := InterfaceInfoCollectorConfiguration{
+		Name:   "Test",
+		Fields: []string{"interface_name", "link_up", "unknown"},
+	}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+
+	select {
+	case stat := <-aggr.Ch:
+		expected := `{"interfaces":[{"interface_index":1,"interface_name":"GigabitEthernet0/8/0","link_up":true}]}`
+		if json := string(util.JsonOf(stat)); json != expected {
+			t.Errorf("Received invalid interfaces, expected: %v, received: %v", expected, json)
+		}
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Error("Timed out. Did not receive interfaces")
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Package ifc_registry keeps track of interfaces present in VPP, so that interface-indexed stats can be enriched with
+interface attributes before they reach the aggregators.
+*/
+package ifc_registry
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"net"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"sync"
+	"time"
+)
+
+// Minimal delay between refreshes triggered by lookups of unknown interfaces
+const MIN_REFRESH_INTERVAL = time.Second
+
+// Attributes of an interface, embedded into interface-indexed stats
+type Interface struct {
+	InterfaceName string `json:"interface_name,omitempty"`
+	L2Address     string `json:"l2_address,omitempty"`
+	Tag           string `json:"tag,omitempty"`
+}
+
+// Interfaces of a single VPP connection, fed by sw_interface_dump and interface events
+type Registry struct {
+	connection   *govpp.VppConnection
+	subscription *govpp.Subscription
+	lock         sync.RWMutex
+	interfaces   map[uint]Interface
+	lastRefresh  time.Time
+	refreshing   bool
+	// Another refresh was requested while refreshing
+	pending  bool
+	released bool
+	// Cancelled on release, stopping refreshes running in the background
+	ctx       context.Context
+	cancel    context.CancelFunc
+	refreshes sync.WaitGroup
+}
+
+var (
+	registriesLock sync.Mutex
+	registries     = make(map[*govpp.VppConnection]*Registry)
+)
+
+// Registry of connection, shared by all collectors using the connection. Created on first use.
+func For(connection *govpp.VppConnection) *Registry {
+	registriesLock.Lock()
+	defer registriesLock.Unlock()
+
+	if registry, isPresent := registries[connection]; isPresent {
+		return registry
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	registry := &Registry{
+		connection: connection,
+		ctx:        ctx,
+		cancel:     cancel,
+		interfaces: make(map[uint]Interface),
+	}
+	registry.subscription = connection.Subscribe(&api.SwInterfaceEvent{}, registry.interfaceEventCallback)
+	registries[connection] = registry
+
+	log.WithField("connection", connection.String()).Debug("Interface registry created successfully")
+	return registry
+}
+
+// Drop registry of connection, to be invoked before the connection is disconnected. Cancels refreshes running
+// in the background and waits for them to finish, so that the connection is no longer used once this returns.
+func Release(connection *govpp.VppConnection) {
+	registriesLock.Lock()
+	registry, isPresent := registries[connection]
+	delete(registries, connection)
+	registriesLock.Unlock()
+
+	if !isPresent {
+		return
+	}
+
+	registry.subscription.Unsubscribe()
+
+	registry.lock.Lock()
+	registry.released = true
+	registry.lock.Unlock()
+
+	registry.cancel()
+	registry.refreshes.Wait()
+}
+
+// Reload all interfaces from VPP
+func (s *Registry) Refresh(ctx context.Context) error {
+	allDetails, err := DumpInterfaces(ctx, s.connection)
+	if err != nil {
+		return err
+	}
+
+	s.Update(allDetails)
+	return nil
+}
+
+// Details of all interfaces present in VPP
+func DumpInterfaces(ctx context.Context, connection *govpp.VppConnection) ([]*api.SwInterfaceDetails, error) {
+	replies := connection.SendMultiRequest(ctx, &api.SwInterfaceDump{})
+	defer replies.Close()
+
+	var allDetails []*api.SwInterfaceDetails
+	for {
+		reply, err := replies.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to dump interfaces: %w", err)
+		}
+		if reply == nil {
+			return allDetails, nil
+		}
+		allDetails = append(allDetails, reply.(*api.SwInterfaceDetails))
+	}
+}
+
+// Replace all interfaces with a complete sw_interface_dump, making it available to all collectors of the connection
+func (s *Registry) Update(allDetails []*api.SwInterfaceDetails) {
+	interfaces := make(map[uint]Interface, len(allDetails))
+	for _, details := range allDetails {
+		interfaces[uint(details.SwIfIndex)] = InterfaceOf(details)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.interfaces = interfaces
+	s.lastRefresh = time.Now()
+
+	log.WithField("interfaces", len(interfaces)).Debug("Interface registry updated")
+}
+
+// Attributes of interface with ifcIndex. Unknown interface triggers a refresh in the background, empty
+// attributes are returned meanwhile.
+func (s *Registry) Lookup(ifcIndex uint) Interface {
+	s.lock.RLock()
+	ifc, isKnown := s.interfaces[ifcIndex]
+	s.lock.RUnlock()
+
+	if !isKnown {
+		s.refreshLater(false)
+	}
+	return ifc
+}
+
+// Interfaces get created or deleted, deleted interfaces are kept until the next refresh so that their
+// deletion can still be reported with their attributes
+func (s *Registry) interfaceEventCallback(msg api.Message, _ uint) {
+	event := msg.(*api.SwInterfaceEvent)
+
+	s.lock.RLock()
+	_, isKnown := s.interfaces[uint(event.SwIfIndex)]
+	s.lock.RUnlock()
+
+	if event.Deleted == 1 || !isKnown {
+		s.refreshLater(true)
+	}
+}
+
+// Refresh in the background. Lookups are throttled, events are not since they signal a change in VPP.
+// Never blocks, since it is invoked from message handlers and the refresh needs them to receive its replies.
+func (s *Registry) refreshLater(force bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.released {
+		return
+	}
+	if s.refreshing {
+		s.pending = s.pending || force
+		return
+	}
+	if !force && time.Since(s.lastRefresh) < MIN_REFRESH_INTERVAL {
+		return
+	}
+
+	s.refreshing = true
+	s.refreshes.Add(1)
+	go s.refreshInBackground()
+}
+
+func (s *Registry) refreshInBackground() {
+	defer s.refreshes.Done()
+
+	for {
+		ctx, cancel := context.WithTimeout(s.ctx, collector.DEFAULT_TIMEOUT)
+		err := s.Refresh(ctx)
+		cancel()
+
+		s.lock.Lock()
+		if err != nil {
+			// Do not retry right away on lookups
+			s.lastRefresh = time.Now()
+			log.WithField("error", err).Debug("Unable to refresh interface registry")
+		}
+		if !s.pending || s.released {
+			s.refreshing = false
+			s.pending = false
+			s.lock.Unlock()
+			return
+		}
+		s.pending = false
+		s.lock.Unlock()
+	}
+}
+
+// Attributes of interface
+func InterfaceOf(details *api.SwInterfaceDetails) Interface {
+	return Interface{
+		InterfaceName: details.InterfaceName,
+		L2Address:     L2Address(details),
+		Tag:           details.Tag,
+	}
+}
+
+// Formatted L2 address of interface
+func L2Address(details *api.SwInterfaceDetails) string {
+	if l2AddrLength := int(details.L2AddressLength); l2AddrLength > 0 && l2AddrLength <= len(details.L2Address) {
+		return net.HardwareAddr(details.L2Address[:l2AddrLength]).String()
+	}
+	return ""
+}
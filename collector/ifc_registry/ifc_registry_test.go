@@ -0,0 +1,105 @@
+package ifc_registry
+
+import (
+	"context"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer Release(connection)
+
+	server.SetInterfaces(api.SwInterfaceDetails{
+		SwIfIndex:       3,
+		InterfaceName:   "GigabitEthernet0/8/0",
+		L2AddressLength: 6,
+		L2Address:       []byte{0x08, 0x00, 0x27, 0x1b, 0x2c, 0x3d, 0, 0},
+		Tag:             "uplink",
+	})
+
+	registry := For(connection)
+	if For(connection) != registry {
+		t.Error("Registry not shared for a single connection")
+	}
+	if err := registry.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	expected := Interface{InterfaceName: "GigabitEthernet0/8/0", L2Address: "08:00:27:1b:2c:3d", Tag: "uplink"}
+	if ifc := registry.Lookup(3); ifc != expected {
+		t.Errorf("Invalid interface, expected: %v, received: %v", expected, ifc)
+	}
+}
+
+func TestRefreshOnInterfaceEvent(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer Release(connection)
+
+	registry := For(connection)
+	if err := registry.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if _, err := connection.SendRequest(context.Background(),
+		&api.WantInterfaceEvents{EnableDisable: 1, Pid: uint32(connection.GetPid())}); err != nil {
+		t.Fatalf("Unable to enable interface events: %v", err)
+	}
+
+	// New interface is announced by an event, registry reloads interfaces on its own
+	server.SetInterfaces(api.SwInterfaceDetails{SwIfIndex: 1, InterfaceName: "loop0"})
+	server.InjectInterfaceEvent(1, true, true, false)
+
+	if err := server.WaitForRequests(&api.SwInterfaceDump{}, 2, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second * time.Duration(5))
+	for registry.Lookup(1).InterfaceName != "loop0" {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out. Registry did not pick up new interface")
+		}
+		time.Sleep(time.Millisecond * time.Duration(10))
+	}
+}
+
+func TestReleaseCancelsRefresh(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+
+	// Dump never finishes, until the test is over
+	dumping := make(chan (int), 1)
+	block := make(chan (int))
+	defer close(block)
+	server.RegisterHandler(&api.SwInterfaceDump{}, func(uint32, api.Message) []api.Message {
+		dumping <- 1
+		<-block
+		return nil
+	})
+
+	// Unknown interface triggers a refresh in the background
+	registry := For(connection)
+	registry.Lookup(1)
+	select {
+	case <-dumping:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Refresh not started")
+	}
+
+	released := make(chan (int))
+	go func() {
+		Release(connection)
+		close(released)
+	}()
+
+	select {
+	case <-released:
+	case <-time.After(time.Second * time.Duration(2)):
+		t.Fatal("Timed out. Release did not cancel the refresh")
+	}
+
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	if registry.refreshing {
+		t.Error("Refresh still running after release")
+	}
+}
@@ -0,0 +1,185 @@
+package ifc_state
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector/ifc_registry"
+	"pnda/vpp/monitoring/util"
+	"sync"
+	"time"
+)
+
+// Default sliding window for flap detection in seconds
+const DEFAULT_FLAP_WINDOW = 60
+
+// Interface changed its link state at least the configured number of times within the window
+type interfaceFlapping struct {
+	InterfaceIndex uint `json:"interface_index"`
+	ifc_registry.Interface
+	Transitions   uint    `json:"transitions"`
+	WindowSeconds float64 `json:"window_seconds"`
+}
+
+// Flapping interface did not change its link state for a whole window
+type interfaceStable struct {
+	InterfaceIndex uint `json:"interface_index"`
+	ifc_registry.Interface
+	LinkState bool `json:"link_state"`
+	// All transitions while the interface was flapping
+	Transitions   uint    `json:"transitions"`
+	WindowSeconds float64 `json:"window_seconds"`
+}
+
+// Link state history of a single interface
+type linkHistory struct {
+	ifc ifc_registry.Interface
+	// Link state known, either from a snapshot or from an event
+	known       bool
+	linkState   bool
+	transitions []time.Time
+	flapping    bool
+	// Transitions since the interface started flapping
+	flapTransitions uint
+	stableTimer     *time.Timer
+}
+
+// Counts link transitions per interface in a sliding window
+type flapDetector struct {
+	threshold  uint
+	window     time.Duration
+	lock       sync.Mutex
+	histories  map[uint]*linkHistory
+	aggregator aggregator.CollectorAggregator
+}
+
+func newFlapDetector(threshold uint, window time.Duration, aggregator aggregator.CollectorAggregator) *flapDetector {
+	return &flapDetector{
+		threshold:  threshold,
+		window:     window,
+		histories:  make(map[uint]*linkHistory),
+		aggregator: aggregator,
+	}
+}
+
+// Record current link state without counting it as a transition, e.g. from a snapshot
+func (s *flapDetector) reset(ifcIndex uint, ifc ifc_registry.Interface, linkState bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	history := s.history(ifcIndex)
+	history.ifc = ifc
+	history.known = true
+	history.linkState = linkState
+}
+
+// Record link state of an interface, returns true if the interface is flapping. Publishes interfaceFlapping once
+// the interface starts flapping.
+func (s *flapDetector) linkStateChanged(ifcIndex uint, ifc ifc_registry.Interface, linkState bool, now time.Time) bool {
+	s.lock.Lock()
+	history := s.history(ifcIndex)
+	history.ifc = ifc
+	if !history.known || history.linkState == linkState {
+		// First state seen or admin state change only
+		history.known = true
+		history.linkState = linkState
+		flapping := history.flapping
+		s.lock.Unlock()
+		return flapping
+	}
+	history.linkState = linkState
+
+	// Drop transitions out of the window
+	recent := history.transitions[:0]
+	for _, transition := range history.transitions {
+		if now.Sub(transition) < s.window {
+			recent = append(recent, transition)
+		}
+	}
+	history.transitions = append(recent, now)
+
+	var flapping aggregator.Stat
+	if history.flapping {
+		history.flapTransitions++
+		history.stableTimer.Reset(s.window)
+	} else if uint(len(history.transitions)) >= s.threshold {
+		history.flapping = true
+		history.flapTransitions = uint(len(history.transitions))
+		history.stableTimer = time.AfterFunc(s.window, func() { s.stable(ifcIndex, history) })
+		flapping = interfaceFlapping{ifcIndex, ifc, uint(len(history.transitions)), s.window.Seconds()}
+	}
+	isFlapping := history.flapping
+	aggr := s.aggregator
+	s.lock.Unlock()
+
+	if flapping != nil {
+		log.WithField("interface-flapping", util.StringOf(flapping)).Warn("Interface flapping")
+		publish(aggr, flapping)
+	}
+	return isFlapping
+}
+
+// Window elapsed without a transition of a flapping interface
+func (s *flapDetector) stable(ifcIndex uint, history *linkHistory) {
+	s.lock.Lock()
+	if !history.flapping || s.histories[ifcIndex] != history {
+		// Interface deleted or detector closed meanwhile
+		s.lock.Unlock()
+		return
+	}
+	if last := history.transitions[len(history.transitions)-1]; time.Since(last) < s.window {
+		// Transition while the timer was firing, the timer was reset already
+		s.lock.Unlock()
+		return
+	}
+
+	stable := interfaceStable{ifcIndex, history.ifc, history.linkState, history.flapTransitions, s.window.Seconds()}
+	history.flapping = false
+	history.flapTransitions = 0
+	history.transitions = nil
+	history.stableTimer = nil
+	aggr := s.aggregator
+	s.lock.Unlock()
+
+	log.WithField("interface-stable", util.StringOf(stable)).Info("Interface stable again")
+	publish(aggr, stable)
+}
+
+// Forget a deleted interface
+func (s *flapDetector) deleted(ifcIndex uint) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if history, isPresent := s.histories[ifcIndex]; isPresent && history.stableTimer != nil {
+		history.stableTimer.Stop()
+	}
+	delete(s.histories, ifcIndex)
+}
+
+// Stop all timers, nothing is published afterwards
+func (s *flapDetector) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, history := range s.histories {
+		if history.stableTimer != nil {
+			history.stableTimer.Stop()
+		}
+	}
+	s.histories = make(map[uint]*linkHistory)
+	s.aggregator = nil
+}
+
+func (s *flapDetector) history(ifcIndex uint) *linkHistory {
+	history, isPresent := s.histories[ifcIndex]
+	if !isPresent {
+		history = &linkHistory{}
+		s.histories[ifcIndex] = history
+	}
+	return history
+}
+
+func publish(aggr aggregator.CollectorAggregator, stat aggregator.Stat) {
+	if aggr != nil {
+		aggr.Channel() <- stat
+	}
+}
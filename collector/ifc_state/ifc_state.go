@@ -1,151 +1,166 @@
 package ifc_state
 
-/*
-#cgo LDFLAGS: -L/usr/lib/x86_64-linux-gnu -lvlibmemoryclient -lvlibapi -lsvm -lvppinfra -lpthread -lm -lrt -lpneum
-
-#include <vnet/vnet.h>
-#include <vlib/vlib.h>
-#include <vlib/unix/unix.h>
-#include <vlibapi/api.h>
-#include <vlibmemory/api.h>
-
-#include <vpp/api/vpe_msg_enum.h>
-
-#define vl_typedefs
-#include <vpp/api/vpe_all_api_h.h>
-#undef vl_typedefs
-
-#define vl_endianfun
-#include <vpp/api/vpe_all_api_h.h>
-#undef vl_endianfun
-
-// Callback cannot be in Go, since it's impossible to send pointer to a Go func to C
-void wantEventsCallback(u32 retval);
-static inline void vl_api_want_interface_events_reply_t_handler(vl_api_want_interface_events_reply_t * mp) {
-	wantEventsCallback(clib_net_to_host_u32(mp->retval));
-}
-
-void ifcStateChangeCallback(u32 index, u8 admin, u8 link, u8 deleted);
-static inline void vl_api_sw_interface_set_flags_t_handler(vl_api_sw_interface_set_flags_t * mp) {
-	ifcStateChangeCallback(clib_net_to_host_u32(mp->sw_if_index), mp->admin_up_down, mp->link_up_down, mp->deleted);
-}
-
-static inline void register_callback() {
-	vl_msg_api_set_handlers(VL_API_WANT_INTERFACE_EVENTS_REPLY, "want_interface_events_reply",
-	vl_api_want_interface_events_reply_t_handler,
-	vl_noop_handler, vl_noop_handler, vl_noop_handler,
-	sizeof(vl_api_want_interface_events_reply_t), 1);
-
-	vl_msg_api_set_handlers(VL_API_SW_INTERFACE_SET_FLAGS, "sw_interface_set_flags",
-	vl_api_sw_interface_set_flags_t_handler,
-	vl_noop_handler, vl_noop_handler, vl_noop_handler,
-	sizeof(vl_api_sw_interface_set_flags_t), 1);
-}
-
-static inline vl_api_want_interface_events_t* new_request(u32 client_id, u32 enable, u32 pid) {
-	vl_api_want_interface_events_t * mp;
-	mp = vl_msg_api_alloc(sizeof(*mp));
-	memset (mp, 0, sizeof (*mp));
-
-	mp->_vl_msg_id = ntohs (VL_API_WANT_INTERFACE_EVENTS);
-	mp->client_index = client_id;
-	mp->enable_disable = enable;
-	mp->pid = pid;
-	return mp;
-}
-*/
-import "C"
 import (
+	"context"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"pnda/vpp/monitoring/aggregator"
 	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/collector/ifc_registry"
 	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
 	"pnda/vpp/monitoring/util"
-	"unsafe"
+	"time"
 )
 
 type InterfaceStateChangesCollectorConfiguration struct {
 	Name string
+	// Number of link transitions within FlapWindow for an interface to be reported as interfaceFlapping,
+	// 0 disables flap detection
+	FlapThreshold float64
+	// Sliding window for flap detection in seconds, defaults to 60. A flapping interface is reported as
+	// interfaceStable once its link did not change for a whole window.
+	FlapWindow float64
+	// Do not report state changes of flapping interfaces
+	DampFlapping bool
 }
 
-var singletonCollector *interfaceStateCollector = nil
-
 func (s InterfaceStateChangesCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator) collector.Collector {
-	if singletonCollector != nil {
-		log.WithFields(log.Fields{
-			"collector": singletonCollector,
-		}).Panic("Collector(singleton) already exists")
-	}
-
-	C.register_callback()
-
-	singletonCollector = &interfaceStateCollector{
+	clctr := &interfaceStateCollector{
 		configuration: s,
 		aggregator:    aggregator,
 	}
 
+	if s.FlapThreshold > 0 {
+		window := s.FlapWindow
+		if window <= 0 {
+			window = DEFAULT_FLAP_WINDOW
+		}
+		clctr.flapDetector = newFlapDetector(uint(s.FlapThreshold),
+			time.Duration(window*float64(time.Second)), aggregator)
+	}
+
 	log.WithFields(log.Fields{
-		"collector": singletonCollector,
+		"collector": clctr,
 	}).Debug("InterfaceStateCollector created successfully")
 
-	return singletonCollector
+	return clctr
 }
 
 type interfaceStateCollector struct {
 	configuration InterfaceStateChangesCollectorConfiguration
 	aggregator    aggregator.CollectorAggregator
+	subscription  *govpp.Subscription
+	registry      *ifc_registry.Registry
+	// Nil if flap detection is disabled
+	flapDetector *flapDetector
 }
 
 type interfaceStateChange struct {
 	InterfaceIndex uint `json:"interface_index"`
-	AdminState     bool `json:"admin_state"`
-	LinkState      bool `json:"link_state"`
+	ifc_registry.Interface
+	AdminState bool `json:"admin_state"`
+	LinkState  bool `json:"link_state"`
+	// Current state reported on subscription rather than a change
+	Snapshot bool `json:"snapshot"`
 }
 
 type interfaceDeleted struct {
 	InterfaceIndex uint `json:"interface_index"`
+	ifc_registry.Interface
 }
 
-//export wantEventsCallback
-func wantEventsCallback(retval C.u32) {
-	if retval < 0 {
-		log.WithField("retval", retval).Panic("Interface events activation failed")
-	}
+func (s *interfaceStateCollector) ifcStateChangeCallback(msg api.Message, _ uint) {
+	event := msg.(*api.SwInterfaceEvent)
 
-	log.WithFields(log.Fields{
-		"retval": retval,
-	}).Debug("Successfully activated interface state notifications")
-}
+	ifcIndex := uint(event.SwIfIndex)
+	ifc := s.registry.Lookup(ifcIndex)
 
-//export ifcStateChangeCallback
-func ifcStateChangeCallback(index C.u32, admin C.u8, link C.u8, deleted C.u8) {
 	var ifcStateChange interface{}
-	if byte(deleted) == 1 {
-		ifcStateChange = interfaceDeleted{uint(index)}
+	if event.Deleted == 1 {
+		ifcStateChange = interfaceDeleted{ifcIndex, ifc}
+		if s.flapDetector != nil {
+			s.flapDetector.deleted(ifcIndex)
+		}
 	} else {
-		ifcStateChange = interfaceStateChange{uint(index), admin != 0, link != 0}
+		ifcStateChange = interfaceStateChange{ifcIndex, ifc, event.AdminUpDown != 0, event.LinkUpDown != 0, false}
+		if s.flapDetector != nil {
+			flapping := s.flapDetector.linkStateChanged(ifcIndex, ifc, event.LinkUpDown != 0, time.Now())
+			if flapping && s.configuration.DampFlapping {
+				log.WithFields(log.Fields{
+					"interface-state-update": util.StringOf(ifcStateChange),
+				}).Debug("Interface flapping, damping state change")
+				return
+			}
+		}
 	}
 
 	log.WithFields(log.Fields{
 		"interface-state-update": util.StringOf(ifcStateChange),
 	}).Debug("Received ifc state change notification")
 
-	singletonCollector.aggregator.Channel() <- ifcStateChange
+	s.aggregator.Channel() <- ifcStateChange
 }
 
-func (s interfaceStateCollector) Collect(connection *govpp.VppConnection) {
-	C.register_callback()
+func (s *interfaceStateCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	if s.registry == nil {
+		s.registry = ifc_registry.For(connection)
+	}
+
+	if s.subscription == nil {
+		s.subscription = connection.Subscribe(&api.SwInterfaceEvent{}, s.ifcStateChangeCallback)
+	}
+
+	request := &api.WantInterfaceEvents{EnableDisable: 1, Pid: uint32(connection.GetPid())}
+	if _, err := connection.SendRequest(ctx, request); err != nil {
+		return fmt.Errorf("Unable to activate interface state notifications: %w", err)
+	}
+
+	log.Debug("Successfully activated interface state notifications")
 
-	connection.SendMessage(
-		unsafe.Pointer(
-			C.new_request(
-				C.u32(connection.ClientIndex),
-				C.clib_host_to_net_u32(C.u32(1)),
-				C.clib_host_to_net_u32(C.u32(connection.Pid)))))
+	// Report current state once notifications are enabled, so that no change gets lost in between
+	allDetails, err := ifc_registry.DumpInterfaces(ctx, connection)
+	if err != nil {
+		return fmt.Errorf("Unable to report interface state snapshot: %w", err)
+	}
+	// Interfaces might have changed if VPP restarted
+	s.registry.Update(allDetails)
+
+	for _, details := range allDetails {
+		snapshot := interfaceStateChange{
+			InterfaceIndex: uint(details.SwIfIndex),
+			Interface:      ifc_registry.InterfaceOf(details),
+			AdminState:     details.AdminUpDown != 0,
+			LinkState:      details.LinkUpDown != 0,
+			Snapshot:       true,
+		}
+		if s.flapDetector != nil {
+			s.flapDetector.reset(snapshot.InterfaceIndex, snapshot.Interface, snapshot.LinkState)
+		}
+
+		log.WithFields(log.Fields{
+			"interface-state": util.StringOf(snapshot),
+		}).Debug("Reporting ifc state snapshot")
+
+		s.aggregator.Channel() <- snapshot
+	}
+	return nil
 }
 
-func (s interfaceStateCollector) Close() {
+// VPP API messages the collector depends on
+func (s *interfaceStateCollector) Messages() []api.Message {
+	return []api.Message{&api.WantInterfaceEvents{}, &api.WantInterfaceEventsReply{}, &api.SwInterfaceEvent{},
+		&api.SwInterfaceDump{}, &api.SwInterfaceDetails{}, &api.ControlPing{}, &api.ControlPingReply{}}
+}
+
+func (s *interfaceStateCollector) Close() {
+	if s.subscription != nil {
+		s.subscription.Unsubscribe()
+		s.subscription = nil
+	}
+	if s.flapDetector != nil {
+		s.flapDetector.close()
+	}
 	s.aggregator = nil
 	s.configuration = InterfaceStateChangesCollectorConfiguration{}
-	singletonCollector = nil
 }
@@ -0,0 +1,184 @@
+package ifc_state
+
+import (
+	"context"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/collector/ifc_registry"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+func TestInterfaceEvents(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	server.SetInterfaces(
+		api.SwInterfaceDetails{SwIfIndex: 1, InterfaceName: "GigabitEthernet0/8/0", Tag: "uplink", AdminUpDown: 1},
+		api.SwInterfaceDetails{SwIfIndex: 2, InterfaceName: "loop0"})
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceStateChangesCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	if err := server.WaitForRequests(&api.WantInterfaceEvents{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectInterfaceEvent(1, true, false, false)
+	server.InjectInterfaceEvent(1, true, true, false)
+	server.InjectInterfaceEvent(2, false, false, true)
+
+	uplink := ifc_registry.Interface{InterfaceName: "GigabitEthernet0/8/0", Tag: "uplink"}
+	loop := ifc_registry.Interface{InterfaceName: "loop0"}
+	expected := []aggregator.Stat{
+		// Current state first
+		interfaceStateChange{InterfaceIndex: 1, Interface: uplink, AdminState: true, Snapshot: true},
+		interfaceStateChange{InterfaceIndex: 2, Interface: loop, Snapshot: true},
+		// Changes afterwards
+		interfaceStateChange{InterfaceIndex: 1, Interface: uplink, AdminState: true, LinkState: false},
+		interfaceStateChange{InterfaceIndex: 1, Interface: uplink, AdminState: true, LinkState: true},
+		interfaceDeleted{InterfaceIndex: 2, Interface: loop},
+	}
+
+	for _, expectedStat := range expected {
+		select {
+		case stat := <-aggr.Ch:
+			if stat != expectedStat {
+				t.Errorf("Received invalid state change, expected: %v, received: %v", expectedStat, stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive state change")
+		}
+	}
+}
+
+func TestMultipleCollectors(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	first := vpptest.NewChannelAggregator(10)
+	second := vpptest.NewChannelAggregator(10)
+	firstClctr := InterfaceStateChangesCollectorConfiguration{Name: "First"}.Create(first)
+	secondClctr := InterfaceStateChangesCollectorConfiguration{Name: "Second"}.Create(second)
+	defer secondClctr.Close()
+
+	for _, clctr := range []collector.Collector{firstClctr, secondClctr} {
+		if err := clctr.Collect(context.Background(), connection); err != nil {
+			t.Fatalf("Collection failed: %v", err)
+		}
+	}
+	if err := server.WaitForRequests(&api.WantInterfaceEvents{}, 2, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectInterfaceEvent(1, true, true, false)
+	for _, aggr := range []*vpptest.ChannelAggregator{first, second} {
+		select {
+		case <-aggr.Ch:
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive state change")
+		}
+	}
+
+	// Closed collector does not receive notifications anymore
+	firstClctr.Close()
+	server.InjectInterfaceEvent(2, true, true, false)
+	select {
+	case <-second.Ch:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Did not receive state change")
+	}
+	select {
+	case stat := <-first.Ch:
+		t.Errorf("Closed collector received state change: %v", stat)
+	case <-time.After(time.Millisecond * time.Duration(100)):
+	}
+}
+
+func TestSnapshotOnResubscription(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	server.SetInterfaces(api.SwInterfaceDetails{SwIfIndex: 1, InterfaceName: "loop0", AdminUpDown: 1, LinkUpDown: 1})
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceStateChangesCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	expected := interfaceStateChange{
+		InterfaceIndex: 1,
+		Interface:      ifc_registry.Interface{InterfaceName: "loop0"},
+		AdminState:     true,
+		LinkState:      true,
+		Snapshot:       true,
+	}
+
+	// Collect is executed again after VPP restarts
+	for i := 0; i < 2; i++ {
+		if err := clctr.Collect(context.Background(), connection); err != nil {
+			t.Fatalf("Collection failed: %v", err)
+		}
+
+		select {
+		case stat := <-aggr.Ch:
+			if stat != expected {
+				t.Errorf("Received invalid snapshot, expected: %v, received: %v", expected, stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatal("Timed out. Did not receive snapshot")
+		}
+	}
+}
+
+func TestFlapDetection(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	defer ifc_registry.Release(connection)
+
+	server.SetInterfaces(api.SwInterfaceDetails{SwIfIndex: 1, InterfaceName: "loop0", AdminUpDown: 1})
+
+	aggr := vpptest.NewChannelAggregator(10)
+	clctr := InterfaceStateChangesCollectorConfiguration{
+		Name:          "Test",
+		FlapThreshold: 3,
+		FlapWindow:    0.5,
+		DampFlapping:  true,
+	}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+
+	server.InjectInterfaceEvent(1, true, true, false)
+	server.InjectInterfaceEvent(1, true, false, false)
+	server.InjectInterfaceEvent(1, true, true, false)
+	server.InjectInterfaceEvent(1, true, false, false)
+
+	loop := ifc_registry.Interface{InterfaceName: "loop0"}
+	expected := []aggregator.Stat{
+		interfaceStateChange{InterfaceIndex: 1, Interface: loop, AdminState: true, Snapshot: true},
+		interfaceStateChange{InterfaceIndex: 1, Interface: loop, AdminState: true, LinkState: true},
+		interfaceStateChange{InterfaceIndex: 1, Interface: loop, AdminState: true, LinkState: false},
+		// Third transition within the window, this and further changes are damped
+		interfaceFlapping{InterfaceIndex: 1, Interface: loop, Transitions: 3, WindowSeconds: 0.5},
+		// No transition for a whole window
+		interfaceStable{InterfaceIndex: 1, Interface: loop, LinkState: false, Transitions: 4, WindowSeconds: 0.5},
+	}
+
+	for _, expectedStat := range expected {
+		select {
+		case stat := <-aggr.Ch:
+			if stat != expectedStat {
+				t.Errorf("Received invalid stat, expected: %v, received: %v", expectedStat, stat)
+			}
+		case <-time.After(time.Second * time.Duration(5)):
+			t.Fatalf("Timed out. Did not receive %v", expectedStat)
+		}
+	}
+}
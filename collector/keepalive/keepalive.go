@@ -1,9 +1,13 @@
 package keepalive
 
 import (
+	"context"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/aggregator"
 	"pnda/vpp/monitoring/collector"
 	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
 	"time"
 )
 
@@ -12,55 +16,114 @@ type KeepaliveCollectorConfiguration struct {
 	Timeout float64
 }
 
-var singletonCollector *keepaliveExecutor = nil
-
-func (s KeepaliveCollectorConfiguration) Create(keepaliveFailureChannel chan (int)) collector.Collector {
-	if singletonCollector != nil {
-		log.WithFields(log.Fields{
-			"collector": singletonCollector,
-		}).Panic("Collector(singleton) already exists")
-	}
-
-	singletonCollector = &keepaliveExecutor{
+// Create keepalive executor publishing a stat per ping into aggregator (optional, nil disables the stats),
+// signalling ping failures into keepaliveFailureChannel and new VPP pids after a restart into vppRestartChannel
+func (s KeepaliveCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator,
+	keepaliveFailureChannel chan (int), vppRestartChannel chan (uint)) collector.Collector {
+	clctr := &keepaliveExecutor{
 		configuration:           s,
+		aggregator:              aggregator,
 		keepaliveFailureChannel: keepaliveFailureChannel,
+		vppRestartChannel:       vppRestartChannel,
 	}
 
 	log.WithFields(log.Fields{
-		"collector": singletonCollector,
+		"collector": clctr,
 	}).Debug("KeepaliveCollector created successfully")
 
-	return singletonCollector
+	return clctr
 }
 
 type keepaliveExecutor struct {
 	configuration           KeepaliveCollectorConfiguration
+	aggregator              aggregator.CollectorAggregator
 	keepaliveFailureChannel chan (int)
+	vppRestartChannel       chan (uint)
+	consecutiveFailures     uint
+	lastPid                 uint
+}
+
+// Result of a single keepalive ping
+type keepalive struct {
+	Success             bool    `json:"success"`
+	RoundTripMs         float64 `json:"round_trip_ms"`
+	VpePid              uint    `json:"vpe_pid"`
+	ConsecutiveFailures uint    `json:"consecutive_failures"`
+	// VPP pid differs from the previous successful ping, VPP was restarted
+	PidChanged bool `json:"pid_changed"`
 }
 
-func (s keepaliveExecutor) Collect(connection *govpp.VppConnection) {
-	var ch = make(chan (uint))
+func (s keepalive) String() string {
+	return fmt.Sprintf("%#v", s)
+}
+
+func (s *keepaliveExecutor) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(s.configuration.Timeout))
+	defer cancel()
+
+	if s.lastPid == 0 {
+		s.lastPid = connection.GetPid()
+	}
+
+	log.Debug("Executing keepalive ping")
+	start := time.Now()
+	pid, err := connection.Ping(ctx)
+	roundTrip := time.Since(start)
 
-	ctx := connection.NextContextId()
-	log.WithField("ctx", ctx).Debug("Executing keepalive ping")
-	connection.Ping(ctx, func(_, ctx uint) {
-		ch <- ctx
+	if err != nil {
+		s.consecutiveFailures++
+		s.publish(keepalive{Success: false, VpePid: s.lastPid, ConsecutiveFailures: s.consecutiveFailures})
+
+		log.WithFields(log.Fields{
+			"error":                err,
+			"consecutive-failures": s.consecutiveFailures,
+		}).Error("Keepalive failed")
+		select {
+		case s.keepaliveFailureChannel <- -1:
+		default:
+			// Failure already signalled and not yet processed
+		}
+		return err
+	}
+
+	pidChanged := pid != s.lastPid
+	if pidChanged {
+		log.WithFields(log.Fields{
+			"previous-pid": s.lastPid,
+			"pid":          pid,
+		}).Warn("VPP pid changed, VPP was restarted")
+		select {
+		case s.vppRestartChannel <- pid:
+		default:
+			// Restart already signalled and not yet processed
+		}
+	}
+	s.lastPid = pid
+	s.consecutiveFailures = 0
+
+	s.publish(keepalive{
+		Success:     true,
+		RoundTripMs: float64(roundTrip) / float64(time.Millisecond),
+		VpePid:      pid,
+		PidChanged:  pidChanged,
 	})
 
-	select {
-	case <-ch:
-		log.WithField("ctx", ctx).Debug("Keepalive executed successfully")
-		close(ch)
-		break
-	case <-time.After(time.Second * time.Duration(s.configuration.Timeout)):
-		log.WithField("ctx", ctx).Error("Keepalive failed")
-		s.keepaliveFailureChannel <- -1
-		close(ch)
-		break
+	log.Debug("Keepalive executed successfully")
+	return nil
+}
+
+func (s *keepaliveExecutor) publish(stat keepalive) {
+	if s.aggregator != nil {
+		s.aggregator.Channel() <- stat
 	}
 }
 
-func (s keepaliveExecutor) Close() {
+// VPP API messages the collector depends on
+func (s *keepaliveExecutor) Messages() []api.Message {
+	return []api.Message{&api.ControlPing{}, &api.ControlPingReply{}}
+}
+
+func (s *keepaliveExecutor) Close() {
 	s.configuration = KeepaliveCollectorConfiguration{}
-	singletonCollector = nil
+	s.aggregator = nil
 }
@@ -0,0 +1,88 @@
+package keepalive
+
+import (
+	"context"
+	"github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	logrus.SetLevel(logrus.PanicLevel)
+}
+
+func TestKeepalive(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	failureCh := make(chan (int), 1)
+	clctr := KeepaliveCollectorConfiguration{Name: "Test", Timeout: 1}.Create(aggr, failureCh, make(chan (uint), 1))
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Errorf("Keepalive failed: %v", err)
+	}
+	if stat := (<-aggr.Ch).(keepalive); !stat.Success || stat.VpePid != vpptest.DefaultPid || stat.PidChanged {
+		t.Errorf("Unexpected keepalive stat: %v", stat)
+	}
+
+	// VPP stops answering
+	server.RegisterHandler(&api.ControlPing{}, func(uint32, api.Message) []api.Message {
+		return nil
+	})
+
+	if err := clctr.Collect(context.Background(), connection); err == nil {
+		t.Error("Keepalive succeeded against a hung VPP")
+	}
+	// Repeated failure must not block while the first one is still waiting to be processed
+	if err := clctr.Collect(context.Background(), connection); err == nil {
+		t.Error("Keepalive succeeded against a hung VPP")
+	}
+
+	for i := uint(1); i <= 2; i++ {
+		if stat := (<-aggr.Ch).(keepalive); stat.Success || stat.ConsecutiveFailures != i {
+			t.Errorf("Unexpected keepalive stat: %v", stat)
+		}
+	}
+
+	select {
+	case <-failureCh:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Error("Timed out. Keepalive failure not signalled")
+	}
+}
+
+func TestKeepalivePidChange(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+
+	aggr := vpptest.NewChannelAggregator(10)
+	restartCh := make(chan (uint), 1)
+	clctr := KeepaliveCollectorConfiguration{Name: "Test", Timeout: 1}.Create(aggr, make(chan (int), 1), restartCh)
+	defer clctr.Close()
+
+	server.Pid = vpptest.DefaultPid + 1
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Errorf("Keepalive failed: %v", err)
+	}
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Errorf("Keepalive failed: %v", err)
+	}
+
+	if stat := (<-aggr.Ch).(keepalive); !stat.PidChanged || stat.VpePid != vpptest.DefaultPid+1 {
+		t.Errorf("VPP restart not detected: %v", stat)
+	}
+	if stat := (<-aggr.Ch).(keepalive); stat.PidChanged {
+		t.Errorf("Unexpected VPP restart: %v", stat)
+	}
+
+	select {
+	case pid := <-restartCh:
+		if pid != vpptest.DefaultPid+1 {
+			t.Errorf("Unexpected pid of restarted VPP: %v", pid)
+		}
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Error("Timed out. VPP restart not signalled")
+	}
+}
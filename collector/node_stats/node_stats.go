@@ -0,0 +1,164 @@
+package node_stats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const SHOW_RUNTIME = "show runtime"
+
+type NodeStatsCollectorConfiguration struct {
+	Name string
+	// Report nodes without any calls and suspends as well
+	IncludeIdleNodes bool
+}
+
+func (s NodeStatsCollectorConfiguration) Create(aggregator aggregator.CollectorAggregator) collector.Collector {
+	clctr := &nodeStatsCollector{
+		configuration: s,
+		aggregator:    aggregator,
+	}
+
+	log.WithFields(log.Fields{
+		"collector": clctr,
+	}).Debug("NodeStatsCollector created successfully")
+
+	return clctr
+}
+
+type nodeStatsCollector struct {
+	configuration NodeStatsCollectorConfiguration
+	aggregator    aggregator.CollectorAggregator
+}
+
+// Runtime of a single graph node in a single thread, cumulative since VPP start (or clear runtime)
+type nodeStats struct {
+	Thread     uint   `json:"thread"`
+	ThreadName string `json:"thread_name"`
+	Node       string `json:"node"`
+	State      string `json:"state"`
+	Calls      uint64 `json:"calls"`
+	Vectors    uint64 `json:"vectors"`
+	Suspends   uint64 `json:"suspends"`
+	// Average clocks as reported by VPP: per vector, per call for nodes without vectors or per suspend for nodes
+	// without calls
+	Clocks         float64 `json:"clocks"`
+	VectorsPerCall float64 `json:"vectors_per_call"`
+	// 0 for nodes not processing any packets
+	ClocksPerPacket float64 `json:"clocks_per_packet"`
+}
+
+type nodeRuntime struct {
+	Nodes []nodeStats `json:"nodes"`
+}
+
+func (s *nodeStatsCollector) Collect(ctx context.Context, connection *govpp.VppConnection) error {
+	output, err := connection.Cli(ctx, SHOW_RUNTIME)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve node runtime: %w", err)
+	}
+
+	var runtime nodeRuntime
+	for _, stats := range parseRuntime(output) {
+		if stats.Calls == 0 && stats.Suspends == 0 && !s.configuration.IncludeIdleNodes {
+			continue
+		}
+		runtime.Nodes = append(runtime.Nodes, stats)
+	}
+
+	log.WithFields(log.Fields{
+		"nodes": len(runtime.Nodes),
+	}).Debug("Node runtime polled successfully")
+
+	s.aggregator.Channel() <- runtime
+	return nil
+}
+
+// Thread section header, e.g. "Thread 1 vpp_wk_0 (lcore 2)". Missing if VPP runs without workers.
+var threadHeader = regexp.MustCompile(`^Thread (\d+) (\S+)`)
+
+// Parse show runtime output, e.g.:
+//
+//	Thread 0 vpp_main (lcore 0)
+//	Time 10.1, average vectors/node 0.00, last 128 main loops 0.00 per node 0.00
+//	  vector rates in 0.0000e0, out 0.0000e0, drop 0.0000e0, punt 0.0000e0
+//	             Name                 State         Calls          Vectors        Suspends         Clocks       Vectors/Call
+//	api-rx-from-ring                any wait                 0               0              27          2.82e6            0.00
+//	dpdk-input                       polling           2617349              20               0          1.73e5            0.00
+//
+// Lines other than node lines are skipped.
+func parseRuntime(output string) []nodeStats {
+	var allStats []nodeStats
+	thread, threadName := uint(0), "vpp_main"
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := threadHeader.FindStringSubmatch(line); match != nil {
+			index, _ := strconv.ParseUint(match[1], 10, 32)
+			thread, threadName = uint(index), match[2]
+			continue
+		}
+
+		if stats, isNode := parseNode(line); isNode {
+			stats.Thread, stats.ThreadName = thread, threadName
+			allStats = append(allStats, stats)
+		}
+	}
+	return allStats
+}
+
+// Node line consists of node name, state (possibly multiple words), calls, vectors, suspends, clocks and
+// vectors/call
+func parseNode(line string) (nodeStats, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 7 {
+		return nodeStats{}, false
+	}
+	numbers := fields[len(fields)-5:]
+
+	var stats nodeStats
+	var err error
+	if stats.Calls, err = strconv.ParseUint(numbers[0], 10, 64); err != nil {
+		return nodeStats{}, false
+	}
+	if stats.Vectors, err = strconv.ParseUint(numbers[1], 10, 64); err != nil {
+		return nodeStats{}, false
+	}
+	if stats.Suspends, err = strconv.ParseUint(numbers[2], 10, 64); err != nil {
+		return nodeStats{}, false
+	}
+	if stats.Clocks, err = strconv.ParseFloat(numbers[3], 64); err != nil {
+		return nodeStats{}, false
+	}
+
+	stats.Node = fields[0]
+	stats.State = strings.Join(fields[1:len(fields)-5], " ")
+	if stats.Calls > 0 {
+		stats.VectorsPerCall = float64(stats.Vectors) / float64(stats.Calls)
+	}
+	if stats.Vectors > 0 {
+		stats.ClocksPerPacket = stats.Clocks
+	}
+	return stats, true
+}
+
+// VPP API messages the collector depends on
+func (s *nodeStatsCollector) Messages() []api.Message {
+	return []api.Message{&api.CliInband{}, &api.CliInbandReply{}}
+}
+
+func (s *nodeStatsCollector) Close() {
+	s.aggregator = nil
+	s.configuration = NodeStatsCollectorConfiguration{}
+}
@@ -0,0 +1,67 @@
+package node_stats
+
+import (
+	"context"
+	"errors"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const runtimeOutput = `Thread 0 vpp_main (lcore 0)
+Time 10.1, average vectors/node 0.00, last 128 main loops 0.00 per node 0.00
+  vector rates in 0.0000e0, out 0.0000e0, drop 0.0000e0, punt 0.0000e0
+             Name                 State         Calls          Vectors        Suspends         Clocks       Vectors/Call
+acl-plugin-fa-cleaner-process  event wait                0               0               0          0.00e0            0.00
+api-rx-from-ring                any wait                 0               0              27          2.82e6            0.00
+---------------
+Thread 1 vpp_wk_0 (lcore 2)
+Time 10.1, average vectors/node 2.00, last 128 main loops 0.00 per node 0.00
+  vector rates in 1.9826e0, out 1.9826e0, drop 0.0000e0, punt 0.0000e0
+             Name                 State         Calls          Vectors        Suspends         Clocks       Vectors/Call
+dpdk-input                       polling               100             200               0          1.50e2            2.00
+`
+
+func TestCollectNodeStats(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	server.SetCliOutput(SHOW_RUNTIME, runtimeOutput)
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := NodeStatsCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+
+	select {
+	case stat := <-aggr.Ch:
+		// Idle acl-plugin-fa-cleaner-process is left out
+		expected := nodeRuntime{Nodes: []nodeStats{
+			{Thread: 0, ThreadName: "vpp_main", Node: "api-rx-from-ring", State: "any wait", Suspends: 27,
+				Clocks: 2.82e6},
+			{Thread: 1, ThreadName: "vpp_wk_0", Node: "dpdk-input", State: "polling", Calls: 100, Vectors: 200,
+				Clocks: 150, VectorsPerCall: 2, ClocksPerPacket: 150},
+		}}
+		if !reflect.DeepEqual(stat, expected) {
+			t.Errorf("Received invalid node stats, expected: %v, received: %v", expected, stat)
+		}
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Error("Timed out. Did not receive node stats")
+	}
+}
+
+func TestCollectNodeStatsCliFailure(t *testing.T) {
+	_, connection := vpptest.NewConnectedServer(t)
+
+	clctr := NodeStatsCollectorConfiguration{Name: "Test"}.Create(vpptest.NewChannelAggregator(1))
+	defer clctr.Close()
+
+	err := clctr.Collect(context.Background(), connection)
+	var apiErr *api.VppApiError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("Expected VPP API error, received: %v", err)
+	}
+}
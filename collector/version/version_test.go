@@ -0,0 +1,72 @@
+package version
+
+import (
+	"context"
+	"errors"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+func TestCollectVersion(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	server.SetVersion(api.ShowVersionReply{Program: "vpe", Version: "18.10-rc2", BuildDate: "today"})
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := VersionCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	if err := clctr.Collect(context.Background(), connection); err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+
+	select {
+	case stat := <-aggr.Ch:
+		expected := version{Program: "vpe", Version: "18.10-rc2", BuildDate: "today"}
+		if stat != expected {
+			t.Errorf("Received invalid version, expected: %v, received: %v", expected, stat)
+		}
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Error("Timed out. Did not receive version")
+	}
+}
+
+func TestCollectVersionTimeout(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	server.RegisterHandler(&api.ShowVersion{}, func(uint32, api.Message) []api.Message {
+		return nil
+	})
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := VersionCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(100))
+	defer cancel()
+
+	if err := clctr.Collect(ctx, connection); err == nil {
+		t.Error("Collection succeeded against a hung VPP")
+	}
+}
+
+func TestCollectVersionRetval(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+	server.RegisterHandler(&api.ShowVersion{}, func(uint32, api.Message) []api.Message {
+		return []api.Message{&api.ShowVersionReply{Retval: -9}}
+	})
+
+	aggr := vpptest.NewChannelAggregator(1)
+	clctr := VersionCollectorConfiguration{Name: "Test"}.Create(aggr)
+	defer clctr.Close()
+
+	err := clctr.Collect(context.Background(), connection)
+
+	var apiErr *api.VppApiError
+	if !errors.As(err, &apiErr) || apiErr.Name() != "VNET_API_ERROR_UNIMPLEMENTED" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(aggr.Ch) != 0 {
+		t.Error("Version reported despite an error retval")
+	}
+}
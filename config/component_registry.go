@@ -2,15 +2,21 @@ package config
 
 import (
 	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/collector/api_stats"
+	"pnda/vpp/monitoring/collector/error_counters"
+	"pnda/vpp/monitoring/collector/fib_counters"
+	"pnda/vpp/monitoring/collector/ifc_addresses"
 	"pnda/vpp/monitoring/collector/ifc_counters"
 	"pnda/vpp/monitoring/collector/ifc_info"
 	"pnda/vpp/monitoring/collector/ifc_state"
+	"pnda/vpp/monitoring/collector/node_stats"
 	"pnda/vpp/monitoring/collector/version"
 
 	log "github.com/Sirupsen/logrus"
 	"pnda/vpp/monitoring/aggregator"
 	"pnda/vpp/monitoring/producer"
 	"reflect"
+	"time"
 )
 
 var componentConfigTypeRegistry = make(map[string]reflect.Type)
@@ -23,6 +29,11 @@ func init() {
 	addToRegistry(ifc_counters.InterfaceCountersCollectorConfiguration{})
 	addToRegistry(ifc_info.InterfaceInfoCollectorConfiguration{})
 	addToRegistry(ifc_state.InterfaceStateChangesCollectorConfiguration{})
+	addToRegistry(api_stats.ApiStatsCollectorConfiguration{})
+	addToRegistry(fib_counters.FibCountersCollectorConfiguration{})
+	addToRegistry(ifc_addresses.InterfaceAddressesCollectorConfiguration{})
+	addToRegistry(node_stats.NodeStatsCollectorConfiguration{})
+	addToRegistry(error_counters.ErrorCountersCollectorConfiguration{})
 
 	// Aggregators
 	addToRegistry(aggregator.BufferedAggregatorConfiguration{})
@@ -52,12 +63,34 @@ type CollectorWiring struct {
 	Config     collector.CollectorConfiguration
 	Scheduling Scheduling
 	Aggregator string
+	// VPP target the collector belongs to
+	Target string
+	// Time limit for a single collector execution (all its VPP calls)
+	Timeout time.Duration
+	// Number of additional attempts after a failed execution
+	Retries uint
+	// Delay between attempts
+	RetryDelay time.Duration
+}
+
+// Execution settings of the collector
+func (s CollectorWiring) Execution() collector.Execution {
+	return collector.Execution{
+		Name:       s.Name,
+		Target:     s.Target,
+		Timeout:    s.Timeout,
+		Retries:    s.Retries,
+		RetryDelay: s.RetryDelay,
+	}
 }
 
 const SCHEDULING_KEY = "Schedule"
 const DELAY_KEY = "Delay"
 const TYPE_KEY = "Type"
 const AGGREGATOR_KEY = "Aggregator"
+const TIMEOUT_KEY = "Timeout"
+const RETRIES_KEY = "Retries"
+const RETRY_DELAY_KEY = "RetryDelay"
 const NAME_KEY = "Name"
 const CONFIGURATION_KEY = "Configuration"
 
@@ -75,11 +108,29 @@ func newCollectorConfiguration(clctrType string, name string, config map[string]
 		SchedulingDelay: scheduleDelay,
 	}
 
+	timeout := collector.DEFAULT_TIMEOUT
+	if timeoutValue, isPresent := config[TIMEOUT_KEY]; isPresent {
+		timeout = time.Duration(timeoutValue.(float64) * float64(time.Second))
+	}
+
+	var retries uint
+	if retriesValue, isPresent := config[RETRIES_KEY]; isPresent {
+		retries = uint(retriesValue.(float64))
+	}
+
+	retryDelay := collector.DEFAULT_RETRY_DELAY
+	if retryDelayValue, isPresent := config[RETRY_DELAY_KEY]; isPresent {
+		retryDelay = time.Duration(retryDelayValue.(float64) * float64(time.Second))
+	}
+
 	return CollectorWiring{
 		Name:       name,
 		Config:     cfg.Interface().(collector.CollectorConfiguration),
 		Scheduling: scheduling,
 		Aggregator: config[AGGREGATOR_KEY].(string),
+		Timeout:    timeout,
+		Retries:    retries,
+		RetryDelay: retryDelay,
 	}
 }
 
@@ -10,6 +10,8 @@ import (
 	"github.com/ghodss/yaml"
 	"io/ioutil"
 	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/socketclient"
 	"pnda/vpp/monitoring/util"
 	"strings"
 )
@@ -19,6 +21,8 @@ type WiringInput struct {
 	Collectors  map[string]interface{}
 	Aggregators map[string]interface{}
 	Producers   map[string]interface{}
+	Agent       map[string]interface{}
+	Targets     map[string]interface{}
 }
 
 const COLLECTOR_CFG_SUFFIX = "CollectorConfiguration"
@@ -29,11 +33,14 @@ const PRODUCER_CFG_SUFFIX = "ProducerConfiguration"
 func (s WiringInput) Parse() WiringConfiguration {
 	log.WithField("wiring", util.StringOf(s)).Debug("Parsing wiring configuration")
 
-	var collectors []CollectorWiring
-	for name, configMap := range s.Collectors {
-		collectorType := configMap.(map[string]interface{})["Type"].(string) + COLLECTOR_CFG_SUFFIX
-		collectors = append(collectors,
-			newCollectorConfiguration(collectorType, name, configMap.(map[string]interface{})))
+	collectors := parseCollectors(s.Collectors)
+
+	var targets []TargetWiring
+	for name, configMap := range s.Targets {
+		targets = append(targets, newTargetWiring(name, configMap.(map[string]interface{})))
+	}
+	if len(targets) > 0 && len(collectors) > 0 {
+		log.Warn("Top level collectors are ignored, since VPP targets with their own collectors are declared")
 	}
 
 	var aggregators map[string]AggregatorWiring = make(map[string]AggregatorWiring)
@@ -54,17 +61,85 @@ func (s WiringInput) Parse() WiringConfiguration {
 		Collectors:  collectors,
 		Aggregators: aggregators,
 		Producers:   producers,
+		Agent:       newAgentWiring(s.Agent),
+		Targets:     targets,
 	}
 
 	log.WithField("wiring", util.StringOf(wiringConfiguration)).Debug("Wiring configuration parsed")
 	return wiringConfiguration
 }
 
+func parseCollectors(config map[string]interface{}) []CollectorWiring {
+	var collectors []CollectorWiring
+	for name, configMap := range config {
+		collectorType := configMap.(map[string]interface{})["Type"].(string) + COLLECTOR_CFG_SUFFIX
+		collectors = append(collectors,
+			newCollectorConfiguration(collectorType, name, configMap.(map[string]interface{})))
+	}
+	return collectors
+}
+
 // Parsed wiring and configuration for components to create and run
 type WiringConfiguration struct {
 	Collectors  []CollectorWiring
 	Aggregators map[string]AggregatorWiring
 	Producers   []ProducerWiring
+	Agent       AgentWiring
+	// Monitored VPP instances, empty if the wiring declares no targets
+	Targets []TargetWiring
+}
+
+// Monitored VPP instance with its own connection and collectors. Aggregators and producers are shared.
+type TargetWiring struct {
+	Name    string
+	VppUuid aggregator.VppUuid
+	// Connection settings of the target, settings not present in its wiring are inherited from the agent
+	Connection govpp.VppConnectionAttempt
+	Collectors []CollectorWiring
+}
+
+const UUID_KEY = "Uuid"
+const COLLECTORS_KEY = "Collectors"
+
+func newTargetWiring(name string, config map[string]interface{}) TargetWiring {
+	uuid, isPresent := config[UUID_KEY]
+	if !isPresent {
+		log.WithField("target", name).Panic("VPP target is missing its uuid")
+	}
+
+	var collectors []CollectorWiring
+	if collectorsConfig, isPresent := config[COLLECTORS_KEY]; isPresent && collectorsConfig != nil {
+		collectors = withTarget(parseCollectors(collectorsConfig.(map[string]interface{})), name)
+	}
+
+	return TargetWiring{
+		Name:       name,
+		VppUuid:    newVppUuid(uuid.(string)),
+		Connection: withConnectionConfig(govpp.VppConnectionAttempt{}, config, nil),
+		Collectors: collectors,
+	}
+}
+
+// Wiring of the agent's own stats (e.g. VPP reconnections)
+type AgentWiring struct {
+	// Aggregator receiving agent stats, agent stats are not reported if empty
+	Aggregator string
+	// Aggregator receiving a stat per keepalive ping, defaults to Aggregator
+	KeepaliveAggregator string
+}
+
+const KEEPALIVE_AGGREGATOR_KEY = "KeepaliveAggregator"
+
+func newAgentWiring(config map[string]interface{}) AgentWiring {
+	var wiring AgentWiring
+	if aggregatorName, isPresent := config[AGGREGATOR_KEY]; isPresent {
+		wiring.Aggregator = aggregatorName.(string)
+	}
+	wiring.KeepaliveAggregator = wiring.Aggregator
+	if aggregatorName, isPresent := config[KEEPALIVE_AGGREGATOR_KEY]; isPresent {
+		wiring.KeepaliveAggregator = aggregatorName.(string)
+	}
+	return wiring
 }
 
 // Holds parsed arguments to the agent
@@ -75,6 +150,109 @@ type Args struct {
 	LogFile     string
 	Wiring      WiringInput
 	VppUuid     aggregator.VppUuid
+	// VPP API socket, client name and queue depth used to connect to VPP
+	Connection govpp.VppConnectionAttempt
+	// Retry of failed connection attempts to VPP (on startup and after a keepalive failure)
+	ConnectRetry govpp.RetryPolicy
+}
+
+// Name the agent registers under in VPP if not configured otherwise
+const DEFAULT_CLIENT_NAME = "vpp-monitoring-agent"
+
+const SOCKET_KEY = "Socket"
+const CLIENT_NAME_KEY = "ClientName"
+const QUEUE_SIZE_KEY = "QueueSize"
+
+const socketFlag = "vpp-socket"
+const clientNameFlag = "vpp-client-name"
+const queueSizeFlag = "vpp-queue-size"
+
+// VPP connection settings from flags. Settings in the Agent section of the wiring apply unless the
+// corresponding flag was set explicitly.
+func newConnectionAttempt(agentConfig map[string]interface{}, socketPath string, clientName string,
+	queueSize int) govpp.VppConnectionAttempt {
+
+	flagKeys := map[string]string{socketFlag: SOCKET_KEY, clientNameFlag: CLIENT_NAME_KEY, queueSizeFlag: QUEUE_SIZE_KEY}
+	explicitKeys := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicitKeys[flagKeys[f.Name]] = true
+	})
+
+	attempt := govpp.VppConnectionAttempt{
+		Name:       clientName,
+		SocketPath: socketPath,
+		QueueSize:  queueSize,
+	}
+	return withConnectionConfig(attempt, agentConfig, explicitKeys)
+}
+
+// Override connection settings with the ones present in config, except for ignoredKeys
+func withConnectionConfig(attempt govpp.VppConnectionAttempt, config map[string]interface{},
+	ignoredKeys map[string]bool) govpp.VppConnectionAttempt {
+
+	if value, isPresent := config[SOCKET_KEY]; isPresent && !ignoredKeys[SOCKET_KEY] {
+		attempt.SocketPath = value.(string)
+	}
+	if value, isPresent := config[CLIENT_NAME_KEY]; isPresent && !ignoredKeys[CLIENT_NAME_KEY] {
+		attempt.Name = value.(string)
+	}
+	// FIXME all numbers are parsed as float64
+	if value, isPresent := config[QUEUE_SIZE_KEY]; isPresent && !ignoredKeys[QUEUE_SIZE_KEY] {
+		attempt.QueueSize = int(value.(float64))
+	}
+	return attempt
+}
+
+// VPP instances to monitor. If the wiring declares no targets, a single target is made of the top level
+// collectors, VPP uuid and connection settings. Declared targets inherit connection settings they do not set
+// from the agent (flags and the Agent section of the wiring).
+func (s Args) Targets(wiring WiringConfiguration) []TargetWiring {
+	if len(wiring.Targets) > 0 {
+		targets := make([]TargetWiring, len(wiring.Targets))
+		for i, target := range wiring.Targets {
+			target.Connection = inheritConnection(target.Connection, s.Connection)
+			targets[i] = target
+		}
+		return targets
+	}
+
+	return []TargetWiring{{
+		Name:       DEFAULT_TARGET_NAME,
+		VppUuid:    s.VppUuid,
+		Connection: s.Connection,
+		Collectors: withTarget(wiring.Collectors, DEFAULT_TARGET_NAME),
+	}}
+}
+
+// Connection settings of a target, falling back to the agent's settings for the ones the target does not set
+func inheritConnection(target govpp.VppConnectionAttempt, agent govpp.VppConnectionAttempt) govpp.VppConnectionAttempt {
+	if target.SocketPath == "" {
+		target.SocketPath = agent.SocketPath
+	}
+	if target.Name == "" {
+		target.Name = agent.Name
+	}
+	if target.QueueSize == 0 {
+		target.QueueSize = agent.QueueSize
+	}
+	return target
+}
+
+// Name of the single target made of the top level wiring
+const DEFAULT_TARGET_NAME = "default"
+
+// Copy of collectors assigned to target
+func withTarget(collectors []CollectorWiring, target string) []CollectorWiring {
+	assigned := make([]CollectorWiring, len(collectors))
+	for i, clctr := range collectors {
+		clctr.Target = target
+		assigned[i] = clctr
+	}
+	return assigned
+}
+
+func newVppUuid(uuid string) aggregator.VppUuid {
+	return aggregator.VppUuid(fmt.Sprintf("vpp-%s", strings.TrimSpace(uuid)))
 }
 
 // Parse input arguments for the agent
@@ -88,14 +266,27 @@ func ParseFlags() Args {
 		"Specify the output file for vpp-monitoring-agent debug logging")
 	vppUuid := flag.String("vpp-uuid", "",
 		"Specify a uinque ID of a monitored VPP. The ID will be added to the produced data. "+
-			"Using 'hostid' utility can be one way of generating the UUID")
+			"Using 'hostid' utility can be one way of generating the UUID. "+
+			"Not needed if the wiring declares VPP targets, each with its own uuid")
+	socketPath := flag.String(socketFlag, socketclient.DefaultSocketPath,
+		"Specify the VPP API socket (socksvr { socket-name } in VPP startup configuration). "+
+			"Set a distinct socket to monitor one of several VPP instances on the same host. "+
+			"Only the API socket is supported, not the shared memory API segment prefix")
+	clientName := flag.String(clientNameFlag, DEFAULT_CLIENT_NAME,
+		"Specify the client name the agent registers under in VPP")
+	queueSize := flag.Int(queueSizeFlag, socketclient.DefaultQueueSize,
+		"Specify the depth of the queue of messages received from VPP")
+	connectMaxAttempts := flag.Uint("connect-max-attempts", govpp.DefaultRetryPolicy.MaxAttempts,
+		"Maximum number of attempts to connect to VPP before giving up, 0 means waiting for VPP infinitely")
+	connectInitialBackoff := flag.Duration("connect-initial-backoff", govpp.DefaultRetryPolicy.InitialBackoff,
+		"Delay after the first failed attempt to connect to VPP, doubled after each subsequent failure. Has to be >0")
+	connectMaxBackoff := flag.Duration("connect-max-backoff", govpp.DefaultRetryPolicy.MaxBackoff,
+		"Maximum delay between attempts to connect to VPP, 0 means unbounded")
+	connectJitter := flag.Float64("connect-backoff-jitter", govpp.DefaultRetryPolicy.Jitter,
+		"Random deviation of the delay between attempts to connect to VPP as a fraction of the delay")
 
 	flag.Parse()
 
-	if *vppUuid == "" {
-		log.Panic("VPP uuid argument has to be set. See the usage")
-	}
-
 	configContent, err := ioutil.ReadFile(*wiringFile)
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -113,12 +304,30 @@ func ParseFlags() Args {
 		}).Panic("Unable to parse wiring configuration file")
 	}
 
-	return Args{
+	var uuid aggregator.VppUuid
+	if *vppUuid != "" {
+		uuid = newVppUuid(*vppUuid)
+	} else if len(wiring.Targets) == 0 {
+		log.Panic("VPP uuid argument has to be set unless the wiring declares VPP targets. See the usage")
+	}
+
+	args := Args{
 		Profile:     *profilePtr,
 		ProfilePort: *profilePortPtr,
 		Debug:       *debugPtr,
 		LogFile:     *logFile,
-		VppUuid:     aggregator.VppUuid(fmt.Sprintf("vpp-%s", strings.TrimSpace(*vppUuid))),
+		VppUuid:     uuid,
 		Wiring:      wiring,
+		Connection:  newConnectionAttempt(wiring.Agent, *socketPath, *clientName, *queueSize),
+		ConnectRetry: govpp.RetryPolicy{
+			MaxAttempts:    *connectMaxAttempts,
+			InitialBackoff: *connectInitialBackoff,
+			MaxBackoff:     *connectMaxBackoff,
+			Jitter:         *connectJitter,
+		},
+	}
+	if err := args.ConnectRetry.Validate(); err != nil {
+		log.WithField("error", err).Panic("Invalid VPP connect retry configuration")
 	}
+	return args
 }
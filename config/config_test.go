@@ -0,0 +1,114 @@
+package config
+
+import (
+	"flag"
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/govpp"
+	"testing"
+	"time"
+)
+
+func init() {
+	log.SetLevel(log.ErrorLevel)
+}
+
+func TestTargetsInheritConnection(t *testing.T) {
+	wiring := WiringInput{
+		Targets: map[string]interface{}{
+			"numa0": map[string]interface{}{
+				UUID_KEY:   "numa0",
+				SOCKET_KEY: "/run/vpp/api-numa0.sock",
+			},
+			"numa1": map[string]interface{}{
+				UUID_KEY:        "numa1",
+				SOCKET_KEY:      "/run/vpp/api-numa1.sock",
+				CLIENT_NAME_KEY: "numa1-agent",
+				QUEUE_SIZE_KEY:  float64(64),
+			},
+		},
+	}.Parse()
+
+	args := Args{Connection: govpp.VppConnectionAttempt{
+		SocketPath: "/run/vpp-api.sock",
+		Name:       "agent",
+		QueueSize:  16,
+	}}
+
+	expected := map[string]govpp.VppConnectionAttempt{
+		"numa0": {SocketPath: "/run/vpp/api-numa0.sock", Name: "agent", QueueSize: 16},
+		"numa1": {SocketPath: "/run/vpp/api-numa1.sock", Name: "numa1-agent", QueueSize: 64},
+	}
+	targets := args.Targets(wiring)
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %d targets, got %d", len(expected), len(targets))
+	}
+	for _, target := range targets {
+		if target.Connection != expected[target.Name] {
+			t.Errorf("Unexpected connection of target %s: %+v, expected: %+v",
+				target.Name, target.Connection, expected[target.Name])
+		}
+	}
+}
+
+func TestConnectionFlagsOverrideWiring(t *testing.T) {
+	commandLine := flag.CommandLine
+	defer func() { flag.CommandLine = commandLine }()
+	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+
+	socketPath := flag.String(socketFlag, "/run/vpp-api.sock", "")
+	clientName := flag.String(clientNameFlag, DEFAULT_CLIENT_NAME, "")
+	queueSize := flag.Int(queueSizeFlag, 32, "")
+	if err := flag.CommandLine.Parse([]string{"-" + clientNameFlag, "flag-agent"}); err != nil {
+		t.Fatal(err)
+	}
+
+	agentConfig := map[string]interface{}{
+		SOCKET_KEY:      "/run/vpp/api-wiring.sock",
+		CLIENT_NAME_KEY: "wiring-agent",
+		QUEUE_SIZE_KEY:  float64(64),
+	}
+	attempt := newConnectionAttempt(agentConfig, *socketPath, *clientName, *queueSize)
+
+	expected := govpp.VppConnectionAttempt{SocketPath: "/run/vpp/api-wiring.sock", Name: "flag-agent", QueueSize: 64}
+	if attempt != expected {
+		t.Errorf("Unexpected connection: %+v, expected: %+v", attempt, expected)
+	}
+}
+
+func TestCollectorExecutionSettings(t *testing.T) {
+	config := map[string]interface{}{
+		SCHEDULING_KEY:  map[string]interface{}{TYPE_KEY: "Scheduled", DELAY_KEY: float64(10)},
+		AGGREGATOR_KEY:  "Global-aggregator",
+		TIMEOUT_KEY:     float64(2.5),
+		RETRIES_KEY:     float64(3),
+		RETRY_DELAY_KEY: float64(0.5),
+	}
+	wiring := newCollectorConfiguration("version.VersionCollectorConfiguration", "Version", config)
+
+	if wiring.Timeout != 2500*time.Millisecond {
+		t.Errorf("Unexpected timeout: %v", wiring.Timeout)
+	}
+	if wiring.Retries != 3 {
+		t.Errorf("Unexpected retries: %v", wiring.Retries)
+	}
+	if wiring.RetryDelay != 500*time.Millisecond {
+		t.Errorf("Unexpected retry delay: %v", wiring.RetryDelay)
+	}
+	if wiring.Scheduling.SchedulingDelay != 10 {
+		t.Errorf("Unexpected scheduling delay: %v", wiring.Scheduling.SchedulingDelay)
+	}
+}
+
+func TestCollectorExecutionDefaults(t *testing.T) {
+	config := map[string]interface{}{
+		SCHEDULING_KEY: map[string]interface{}{TYPE_KEY: "Once"},
+		AGGREGATOR_KEY: "Global-aggregator",
+	}
+	wiring := newCollectorConfiguration("version.VersionCollectorConfiguration", "Version", config)
+
+	if wiring.Timeout != collector.DEFAULT_TIMEOUT || wiring.Retries != 0 ||
+		wiring.RetryDelay != collector.DEFAULT_RETRY_DELAY {
+		t.Errorf("Unexpected execution settings: %+v", wiring.Execution())
+	}
+}
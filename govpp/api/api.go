@@ -0,0 +1,94 @@
+/*
+Package api provides Go representations of VPP binary API messages together with their wire format encoding.
+*/
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Type of a VPP binary API message, determines the header preceding the message body on the wire
+type MessageType int
+
+const (
+	// Request message header: msg_id, client_index, context
+	RequestMessage MessageType = iota
+	// Reply message header: msg_id, context
+	ReplyMessage
+	// Event message header: msg_id, client_index
+	EventMessage
+	// Other message header: msg_id only
+	OtherMessage
+)
+
+// Interface that each VPP binary API message has to implement
+type Message interface {
+	// Name of the message as defined in the .api file e.g. control_ping
+	GetMessageName() string
+	// CRC of the message definition as a hex string e.g. 51077d14
+	GetCrcString() string
+	// Type of the message, determining its header
+	GetMessageType() MessageType
+}
+
+// Returns message name with its CRC e.g. control_ping_51077d14 as used in VPP message tables
+func NameWithCrc(msg Message) string {
+	return fmt.Sprintf("%s_%s", msg.GetMessageName(), msg.GetCrcString())
+}
+
+var registeredMessages = make(map[string]Message)
+
+// Register a message type, making it known to connections and to reply lookups. Called from init of each
+// file defining messages.
+func RegisterMessage(msg Message) {
+	registeredMessages[msg.GetMessageName()] = msg
+}
+
+// Returns all registered message types
+func AllMessages() []Message {
+	var all []Message
+	for _, msg := range registeredMessages {
+		all = append(all, msg)
+	}
+	return all
+}
+
+// Create a new instance of the same message type as prototype
+func NewMessage(prototype Message) Message {
+	return reflect.New(reflect.TypeOf(prototype).Elem()).Interface().(Message)
+}
+
+// Create a new instance of the reply to a request, following VPP naming conventions:
+// <name> is answered by <name>_reply and <name>_dump by a sequence of <name>_details
+func NewReply(request Message) (Message, error) {
+	var replyName string
+	if name := request.GetMessageName(); strings.HasSuffix(name, "_dump") {
+		replyName = strings.TrimSuffix(name, "_dump") + "_details"
+	} else {
+		replyName = name + "_reply"
+	}
+
+	if prototype, isPresent := registeredMessages[replyName]; isPresent {
+		return NewMessage(prototype), nil
+	}
+	return nil, fmt.Errorf("Unknown reply %v to request %v", replyName, request.GetMessageName())
+}
+
+// Returns retval of a reply message, false if the message has no Retval field
+func Retval(msg Message) (int32, bool) {
+	value := reflect.ValueOf(msg)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return 0, false
+	}
+
+	field := value.FieldByName("Retval")
+	if !field.IsValid() || field.Kind() != reflect.Int32 {
+		return 0, false
+	}
+	return int32(field.Int()), true
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Struct tag used to describe wire format of strings and slices:
+//
+//	`vpp:"size=64"`         fixed size string or byte slice (NUL padded)
+//	`vpp:"sizefrom=Count"`  slice with length taken from another (previous) field
+//
+// A slice without a tag has to be the last field and consumes the rest of the message
+const tagName = "vpp"
+
+// Encode a message into its binary form including the header as determined by message type.
+// All numbers are encoded in network byte order.
+func EncodeMessage(msg Message, msgID uint16, clientIndex uint32, context uint32) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.BigEndian, msgID)
+	switch msg.GetMessageType() {
+	case RequestMessage:
+		binary.Write(buf, binary.BigEndian, clientIndex)
+		binary.Write(buf, binary.BigEndian, context)
+	case ReplyMessage:
+		binary.Write(buf, binary.BigEndian, context)
+	case EventMessage:
+		binary.Write(buf, binary.BigEndian, clientIndex)
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(msg))
+	if err := encodeStruct(buf, value); err != nil {
+		return nil, fmt.Errorf("Unable to encode %v: %v", msg.GetMessageName(), err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode the message ID from the first 2 bytes of a binary message
+func DecodeMessageID(data []byte) (uint16, error) {
+	if len(data) < 2 {
+		return 0, fmt.Errorf("Message too short to contain message ID: %v", len(data))
+	}
+	return binary.BigEndian.Uint16(data), nil
+}
+
+// Decode the context of a binary message, 0 for message types without a context
+func DecodeContext(data []byte, msgType MessageType) (uint32, error) {
+	switch msgType {
+	case RequestMessage:
+		if len(data) < 10 {
+			return 0, fmt.Errorf("Message too short to contain context: %v", len(data))
+		}
+		return binary.BigEndian.Uint32(data[6:]), nil
+	case ReplyMessage:
+		if len(data) < 6 {
+			return 0, fmt.Errorf("Message too short to contain context: %v", len(data))
+		}
+		return binary.BigEndian.Uint32(data[2:]), nil
+	default:
+		return 0, nil
+	}
+}
+
+// Decode binary message (including the header) into msg
+func DecodeMessage(data []byte, msg Message) error {
+	var headerSize int
+	switch msg.GetMessageType() {
+	case RequestMessage:
+		headerSize = 10
+	case ReplyMessage:
+		headerSize = 6
+	case EventMessage:
+		headerSize = 6
+	default:
+		headerSize = 2
+	}
+
+	if len(data) < headerSize {
+		return fmt.Errorf("Message %v too short: %v", msg.GetMessageName(), len(data))
+	}
+
+	value := reflect.ValueOf(msg)
+	if value.Kind() != reflect.Ptr {
+		return fmt.Errorf("Unable to decode %v into a non pointer", msg.GetMessageName())
+	}
+
+	if err := decodeStruct(bytes.NewReader(data[headerSize:]), value.Elem()); err != nil {
+		return fmt.Errorf("Unable to decode %v: %v", msg.GetMessageName(), err)
+	}
+	return nil
+}
+
+type fieldTag struct {
+	size     int
+	sizeFrom string
+}
+
+func parseTag(field reflect.StructField) fieldTag {
+	var tag fieldTag
+	for _, part := range strings.Split(field.Tag.Get(tagName), ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "size":
+			tag.size, _ = strconv.Atoi(kv[1])
+		case "sizefrom":
+			tag.sizeFrom = kv[1]
+		}
+	}
+	return tag
+}
+
+func encodeStruct(buf *bytes.Buffer, value reflect.Value) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if err := encodeValue(buf, value.Field(i), parseTag(field)); err != nil {
+			return fmt.Errorf("field %v: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func encodeValue(buf *bytes.Buffer, value reflect.Value, tag fieldTag) error {
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.Write(buf, binary.BigEndian, value.Interface())
+	case reflect.String:
+		if tag.size == 0 {
+			return fmt.Errorf("string without size")
+		}
+		str := make([]byte, tag.size)
+		copy(str, value.String())
+		buf.Write(str)
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := encodeValue(buf, value.Index(i), fieldTag{}); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		length := value.Len()
+		if tag.size > 0 {
+			length = tag.size
+		}
+		for i := 0; i < length; i++ {
+			if i < value.Len() {
+				if err := encodeValue(buf, value.Index(i), fieldTag{}); err != nil {
+					return err
+				}
+			} else {
+				// Pad fixed size slices
+				if err := encodeValue(buf, reflect.New(value.Type().Elem()).Elem(), fieldTag{}); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Struct:
+		return encodeStruct(buf, value)
+	default:
+		return fmt.Errorf("unsupported kind %v", value.Kind())
+	}
+	return nil
+}
+
+func decodeStruct(reader *bytes.Reader, value reflect.Value) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := parseTag(field)
+
+		length := -1
+		if tag.size > 0 {
+			length = tag.size
+		} else if tag.sizeFrom != "" {
+			sizeField := value.FieldByName(tag.sizeFrom)
+			if !sizeField.IsValid() {
+				return fmt.Errorf("field %v: unknown size field %v", field.Name, tag.sizeFrom)
+			}
+			length = int(sizeField.Uint())
+		}
+
+		if err := decodeValue(reader, value.Field(i), length); err != nil {
+			return fmt.Errorf("field %v: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// Length is only applicable to strings and slices, -1 means consume the rest of the message
+func decodeValue(reader *bytes.Reader, value reflect.Value, length int) error {
+	switch value.Kind() {
+	case reflect.Bool:
+		b, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		value.SetBool(b != 0)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.Read(reader, binary.BigEndian, value.Addr().Interface())
+	case reflect.String:
+		if length < 0 {
+			length = reader.Len()
+		}
+		if length > reader.Len() {
+			return fmt.Errorf("string of %v bytes exceeds remaining %v bytes", length, reader.Len())
+		}
+		str := make([]byte, length)
+		if _, err := io.ReadFull(reader, str); err != nil {
+			return err
+		}
+		if end := bytes.IndexByte(str, 0); end >= 0 {
+			str = str[:end]
+		}
+		value.SetString(string(str))
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := decodeValue(reader, value.Index(i), -1); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		elemSize := wireSize(value.Type().Elem())
+		if elemSize == 0 {
+			return fmt.Errorf("unable to determine element size")
+		}
+		if length < 0 {
+			// Consume the rest
+			length = reader.Len() / elemSize
+		}
+		// Length may come from the wire, do not allocate more than the message can hold
+		if length*elemSize > reader.Len() {
+			return fmt.Errorf("%v elements of %v bytes exceed remaining %v bytes", length, elemSize, reader.Len())
+		}
+		slice := reflect.MakeSlice(value.Type(), length, length)
+		for i := 0; i < length; i++ {
+			if err := decodeValue(reader, slice.Index(i), -1); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+	case reflect.Struct:
+		return decodeStruct(reader, value)
+	default:
+		return fmt.Errorf("unsupported kind %v", value.Kind())
+	}
+	return nil
+}
+
+// Size of a fixed size struct on the wire (no padding)
+func wireSize(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Struct:
+		size := 0
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if tag := parseTag(field); tag.size > 0 {
+				if field.Type.Kind() == reflect.String {
+					size += tag.size
+				} else {
+					size += tag.size * wireSize(field.Type.Elem())
+				}
+				continue
+			}
+			size += wireSize(field.Type)
+		}
+		return size
+	case reflect.Array:
+		return t.Len() * wireSize(t.Elem())
+	case reflect.String:
+		return 1
+	default:
+		return int(t.Size())
+	}
+}
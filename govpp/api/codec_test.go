@@ -0,0 +1,213 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeRequest(t *testing.T) {
+	msg := &WantInterfaceEvents{EnableDisable: 1, Pid: 4242}
+
+	data, err := EncodeMessage(msg, 22, 7, 99)
+	if err != nil {
+		t.Fatalf("Unable to encode: %v", err)
+	}
+
+	if len(data) != 10+8 {
+		t.Errorf("Unexpected encoded length: %v", len(data))
+	}
+
+	if msgID, _ := DecodeMessageID(data); msgID != 22 {
+		t.Errorf("Unexpected message ID: %v", msgID)
+	}
+
+	if ctx, _ := DecodeContext(data, RequestMessage); ctx != 99 {
+		t.Errorf("Unexpected context: %v", ctx)
+	}
+
+	decoded := &WantInterfaceEvents{}
+	if err := DecodeMessage(data, decoded); err != nil {
+		t.Fatalf("Unable to decode: %v", err)
+	}
+
+	if !reflect.DeepEqual(msg, decoded) {
+		t.Errorf("Decoded message differs, expected: %v, received: %v", msg, decoded)
+	}
+}
+
+func TestEncodeDecodeStrings(t *testing.T) {
+	msg := &SwInterfaceDetails{
+		SwIfIndex:       3,
+		L2AddressLength: 6,
+		L2Address:       []byte{1, 2, 3, 4, 5, 6, 0, 0},
+		InterfaceName:   "GigabitEthernet0/8/0",
+		LinkMtu:         1500,
+		Tag:             "uplink",
+	}
+
+	data, err := EncodeMessage(msg, 1, 0, 5)
+	if err != nil {
+		t.Fatalf("Unable to encode: %v", err)
+	}
+
+	if expected := 6 + wireSize(reflect.TypeOf(*msg)); len(data) != expected {
+		t.Errorf("Unexpected encoded length: %v, expected: %v", len(data), expected)
+	}
+
+	decoded := &SwInterfaceDetails{}
+	if err := DecodeMessage(data, decoded); err != nil {
+		t.Fatalf("Unable to decode: %v", err)
+	}
+
+	if !reflect.DeepEqual(msg, decoded) {
+		t.Errorf("Decoded message differs, expected: %v, received: %v", msg, decoded)
+	}
+}
+
+func TestEncodeDecodeSizeFrom(t *testing.T) {
+	msg := &SockclntCreateReply{
+		Index: 1,
+		Count: 2,
+		MessageTable: []MessageTableEntry{
+			{Index: 10, Name: "control_ping_51077d14"},
+			{Index: 11, Name: "control_ping_reply_f6b0b8ca"},
+		},
+	}
+
+	data, err := EncodeMessage(msg, 16, 0, 0)
+	if err != nil {
+		t.Fatalf("Unable to encode: %v", err)
+	}
+
+	decoded := &SockclntCreateReply{}
+	if err := DecodeMessage(data, decoded); err != nil {
+		t.Fatalf("Unable to decode: %v", err)
+	}
+
+	if !reflect.DeepEqual(msg, decoded) {
+		t.Errorf("Decoded message differs, expected: %v, received: %v", msg, decoded)
+	}
+}
+
+// Message with a trailing slice without size, consuming the rest of the message
+type restMessage struct {
+	Count uint32
+	Data  []uint64
+}
+
+func (*restMessage) GetMessageName() string {
+	return "rest_message"
+}
+func (*restMessage) GetCrcString() string {
+	return "00000000"
+}
+func (*restMessage) GetMessageType() MessageType {
+	return OtherMessage
+}
+
+func TestDecodeRest(t *testing.T) {
+	msg := &restMessage{Count: 2, Data: []uint64{1, 2}}
+
+	data, err := EncodeMessage(msg, 30, 0, 0)
+	if err != nil {
+		t.Fatalf("Unable to encode: %v", err)
+	}
+
+	decoded := &restMessage{}
+	if err := DecodeMessage(data, decoded); err != nil {
+		t.Fatalf("Unable to decode: %v", err)
+	}
+
+	if !reflect.DeepEqual(msg, decoded) {
+		t.Errorf("Decoded message differs, expected: %v, received: %v", msg, decoded)
+	}
+}
+
+func TestDecodeCombinedCounters(t *testing.T) {
+	msg := &VnetInterfaceCombinedCounters{
+		VnetCounterType: 1,
+		FirstSwIfIndex:  126,
+		Count:           2,
+		Data:            []VlibCounter{{Packets: 1, Bytes: 100}, {Packets: 2, Bytes: 200}},
+	}
+
+	data, err := EncodeMessage(msg, 31, 0, 0)
+	if err != nil {
+		t.Fatalf("Unable to encode: %v", err)
+	}
+	// u8 type, u32 first index, u32 count and 2 packet + byte pairs
+	if len(data) != 2+1+4+4+2*16 {
+		t.Errorf("Unexpected message size: %v", len(data))
+	}
+
+	decoded := &VnetInterfaceCombinedCounters{}
+	if err := DecodeMessage(data, decoded); err != nil {
+		t.Fatalf("Unable to decode: %v", err)
+	}
+
+	if !reflect.DeepEqual(msg, decoded) {
+		t.Errorf("Decoded message differs, expected: %v, received: %v", msg, decoded)
+	}
+}
+
+func TestDecodeTooShort(t *testing.T) {
+	if err := DecodeMessage([]byte{0, 1, 0}, &ControlPingReply{}); err == nil {
+		t.Error("Decoding of a truncated message succeeded")
+	}
+}
+
+func TestDecodeSizeFromExceedingMessage(t *testing.T) {
+	msg := &SockclntCreateReply{
+		Index:        1,
+		Count:        1,
+		MessageTable: []MessageTableEntry{{Index: 10, Name: "control_ping_51077d14"}},
+	}
+
+	data, err := EncodeMessage(msg, 16, 0, 0)
+	if err != nil {
+		t.Fatalf("Unable to encode: %v", err)
+	}
+
+	// Corrupt the count to claim far more entries than the message holds
+	corrupted := &SockclntCreateReply{Count: 0xffff}
+	header, _ := EncodeMessage(corrupted, 16, 0, 0)
+	data = append(header, data[len(header):]...)
+
+	if err := DecodeMessage(data, &SockclntCreateReply{}); err == nil {
+		t.Error("Decoding of a message with a corrupted count succeeded")
+	}
+}
+
+func TestDecodeTruncatedString(t *testing.T) {
+	data, err := EncodeMessage(&SwInterfaceDetails{InterfaceName: "GigabitEthernet0/8/0"}, 1, 0, 5)
+	if err != nil {
+		t.Fatalf("Unable to encode: %v", err)
+	}
+
+	if err := DecodeMessage(data[:len(data)-10], &SwInterfaceDetails{}); err == nil {
+		t.Error("Decoding of a truncated string succeeded")
+	}
+}
+
+func TestRetvalError(t *testing.T) {
+	if err := RetvalError(&ShowVersion{}, &ShowVersionReply{}); err != nil {
+		t.Errorf("Zero retval reported as error: %v", err)
+	}
+	if err := RetvalError(&SwInterfaceDump{}, &SwInterfaceDetails{}); err != nil {
+		t.Errorf("Reply without retval reported as error: %v", err)
+	}
+	// VNET_API_ERROR_IN_PROGRESS
+	if err := RetvalError(&ShowVersion{}, &ShowVersionReply{Retval: 10}); err != nil {
+		t.Errorf("Positive retval reported as error: %v", err)
+	}
+
+	err := RetvalError(&ShowVersion{}, &ShowVersionReply{Retval: -2})
+	if apiErr, isApiErr := err.(*VppApiError); !isApiErr || apiErr.Name() != "VNET_API_ERROR_INVALID_SW_IF_INDEX" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	unknown := &VppApiError{Request: "show_version", Retval: -12345}
+	if unknown.Name() != "VNET_API_ERROR_UNKNOWN" {
+		t.Errorf("Unexpected name of unknown error: %v", unknown.Name())
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+)
+
+// VPP API error codes as defined in vnet/api_errno.h
+var errorCodes = map[int32]struct {
+	name        string
+	description string
+}{
+	-1:  {"UNSPECIFIED", "Unspecified Error"},
+	-2:  {"INVALID_SW_IF_INDEX", "Invalid sw_if_index"},
+	-3:  {"NO_SUCH_FIB", "No such FIB / VRF"},
+	-4:  {"NO_SUCH_INNER_FIB", "No such inner FIB / VRF"},
+	-5:  {"NO_SUCH_LABEL", "No such label"},
+	-6:  {"NO_SUCH_ENTRY", "No such entry"},
+	-7:  {"INVALID_VALUE", "Invalid value"},
+	-8:  {"INVALID_VALUE_2", "Invalid value #2"},
+	-9:  {"UNIMPLEMENTED", "Unimplemented"},
+	-10: {"INVALID_SW_IF_INDEX_2", "Invalid sw_if_index #2"},
+	-11: {"SYSCALL_ERROR_1", "System call error #1"},
+	-12: {"SYSCALL_ERROR_2", "System call error #2"},
+	-13: {"SYSCALL_ERROR_3", "System call error #3"},
+	-14: {"SYSCALL_ERROR_4", "System call error #4"},
+	-15: {"SYSCALL_ERROR_5", "System call error #5"},
+	-16: {"SYSCALL_ERROR_6", "System call error #6"},
+	-17: {"SYSCALL_ERROR_7", "System call error #7"},
+	-18: {"SYSCALL_ERROR_8", "System call error #8"},
+	-19: {"SYSCALL_ERROR_9", "System call error #9"},
+	-20: {"SYSCALL_ERROR_10", "System call error #10"},
+	-30: {"FEATURE_DISABLED", "Feature disabled by configuration"},
+	-31: {"INVALID_REGISTRATION", "Invalid registration"},
+	-50: {"NEXT_HOP_NOT_IN_FIB", "Next hop not in FIB"},
+	-51: {"UNKNOWN_DESTINATION", "Unknown destination"},
+	-52: {"PREFIX_MATCHES_NEXT_HOP", "Prefix matches next hop"},
+	-53: {"NEXT_HOP_NOT_FOUND_MP", "Next hop not found (multipath)"},
+	-54: {"NO_MATCHING_INTERFACE", "No matching interface for probe"},
+	-55: {"INVALID_VLAN", "Invalid VLAN"},
+	-56: {"VLAN_ALREADY_EXISTS", "VLAN subif already exists"},
+	-57: {"INVALID_SRC_ADDRESS", "Invalid src address"},
+	-58: {"INVALID_DST_ADDRESS", "Invalid dst address"},
+	-59: {"ADDRESS_LENGTH_MISMATCH", "Address length mismatch"},
+	-60: {"ADDRESS_NOT_FOUND_FOR_INTERFACE", "Address not found for interface"},
+	-61: {"ADDRESS_NOT_LINK_LOCAL", "Address not link-local"},
+	-62: {"IP6_NOT_ENABLED", "ip6 not enabled"},
+	10:  {"IN_PROGRESS", "Operation in progress"},
+}
+
+// Negative retval of a reply to a request
+type VppApiError struct {
+	Request string
+	Retval  int32
+}
+
+// Name of the error code as defined by VPP e.g. VNET_API_ERROR_INVALID_SW_IF_INDEX
+func (e *VppApiError) Name() string {
+	if code, isKnown := errorCodes[e.Retval]; isKnown {
+		return "VNET_API_ERROR_" + code.name
+	}
+	return "VNET_API_ERROR_UNKNOWN"
+}
+
+func (e *VppApiError) Error() string {
+	description := "Unknown error"
+	if code, isKnown := errorCodes[e.Retval]; isKnown {
+		description = code.description
+	}
+	return fmt.Sprintf("%v failed with %v (%v): %v", e.Request, e.Name(), e.Retval, description)
+}
+
+// Returns a VppApiError if reply to request carries a negative retval, nil otherwise. VPP uses positive retvals
+// for non-failures, e.g. VNET_API_ERROR_IN_PROGRESS.
+func RetvalError(request Message, reply Message) error {
+	if retval, hasRetval := Retval(reply); hasRetval && retval < 0 {
+		return &VppApiError{Request: request.GetMessageName(), Retval: retval}
+	}
+	return nil
+}
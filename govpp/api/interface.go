@@ -0,0 +1,111 @@
+package api
+
+func init() {
+	RegisterMessage(&SwInterfaceDump{})
+	RegisterMessage(&SwInterfaceDetails{})
+	RegisterMessage(&WantInterfaceEvents{})
+	RegisterMessage(&WantInterfaceEventsReply{})
+	RegisterMessage(&SwInterfaceEvent{})
+}
+
+// Dump of all (or filtered) interfaces, answered by a sequence of SwInterfaceDetails
+type SwInterfaceDump struct {
+	NameFilterValid uint8
+	NameFilter      []byte `vpp:"size=49"`
+}
+
+func (*SwInterfaceDump) GetMessageName() string {
+	return "sw_interface_dump"
+}
+func (*SwInterfaceDump) GetCrcString() string {
+	return "63f5e3b7"
+}
+func (*SwInterfaceDump) GetMessageType() MessageType {
+	return RequestMessage
+}
+
+type SwInterfaceDetails struct {
+	SwIfIndex         uint32
+	SupSwIfIndex      uint32
+	L2AddressLength   uint32
+	L2Address         []byte `vpp:"size=8"`
+	InterfaceName     string `vpp:"size=64"`
+	AdminUpDown       uint8
+	LinkUpDown        uint8
+	LinkDuplex        uint8
+	LinkSpeed         uint8
+	LinkMtu           uint16
+	SubID             uint32
+	SubDot1ad         uint8
+	SubNumberOfTags   uint8
+	SubOuterVlanID    uint16
+	SubInnerVlanID    uint16
+	SubExactMatch     uint8
+	SubDefault        uint8
+	SubOuterVlanIDAny uint8
+	SubInnerVlanIDAny uint8
+	VtrOp             uint32
+	VtrPushDot1q      uint32
+	VtrTag1           uint32
+	VtrTag2           uint32
+	Tag               string `vpp:"size=64"`
+}
+
+func (*SwInterfaceDetails) GetMessageName() string {
+	return "sw_interface_details"
+}
+func (*SwInterfaceDetails) GetCrcString() string {
+	return "e4ee7eb6"
+}
+func (*SwInterfaceDetails) GetMessageType() MessageType {
+	return ReplyMessage
+}
+
+// Subscription to interface state change notifications (SwInterfaceEvent)
+type WantInterfaceEvents struct {
+	EnableDisable uint32
+	Pid           uint32
+}
+
+func (*WantInterfaceEvents) GetMessageName() string {
+	return "want_interface_events"
+}
+func (*WantInterfaceEvents) GetCrcString() string {
+	return "476f5a08"
+}
+func (*WantInterfaceEvents) GetMessageType() MessageType {
+	return RequestMessage
+}
+
+type WantInterfaceEventsReply struct {
+	Retval int32
+}
+
+func (*WantInterfaceEventsReply) GetMessageName() string {
+	return "want_interface_events_reply"
+}
+func (*WantInterfaceEventsReply) GetCrcString() string {
+	return "e8d4e804"
+}
+func (*WantInterfaceEventsReply) GetMessageType() MessageType {
+	return ReplyMessage
+}
+
+// Interface state, sent by VPP as a notification to clients subscribed with WantInterfaceEvents
+type SwInterfaceEvent struct {
+	Pid         uint32
+	SwIfIndex   uint32
+	AdminUpDown uint8
+	LinkUpDown  uint8
+	Deleted     uint8
+}
+
+func (*SwInterfaceEvent) GetMessageName() string {
+	return "sw_interface_event"
+}
+func (*SwInterfaceEvent) GetCrcString() string {
+	return "bf9938e4"
+}
+func (*SwInterfaceEvent) GetMessageType() MessageType {
+	return EventMessage
+}
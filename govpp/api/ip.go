@@ -0,0 +1,40 @@
+package api
+
+func init() {
+	RegisterMessage(&IpAddressDump{})
+	RegisterMessage(&IpAddressDetails{})
+}
+
+// Dump of IPv4 or IPv6 addresses of a single interface, answered by a sequence of IpAddressDetails
+type IpAddressDump struct {
+	SwIfIndex uint32
+	IsIpv6    uint8
+}
+
+func (*IpAddressDump) GetMessageName() string {
+	return "ip_address_dump"
+}
+func (*IpAddressDump) GetCrcString() string {
+	return "6b7bcd0a"
+}
+func (*IpAddressDump) GetMessageType() MessageType {
+	return RequestMessage
+}
+
+// Single address of an interface. IPv4 addresses occupy the first 4 bytes of Ip.
+type IpAddressDetails struct {
+	Ip           []byte `vpp:"size=16"`
+	PrefixLength uint8
+	SwIfIndex    uint32
+	IsIpv6       uint8
+}
+
+func (*IpAddressDetails) GetMessageName() string {
+	return "ip_address_details"
+}
+func (*IpAddressDetails) GetCrcString() string {
+	return "2f1dbc7d"
+}
+func (*IpAddressDetails) GetMessageType() MessageType {
+	return ReplyMessage
+}
@@ -0,0 +1,77 @@
+package api
+
+func init() {
+	RegisterMessage(&SockclntCreate{})
+	RegisterMessage(&SockclntCreateReply{})
+	RegisterMessage(&SockclntDelete{})
+	RegisterMessage(&SockclntDeleteReply{})
+}
+
+// Socket client registration. Its message ID is fixed since the message table is not known before it is sent.
+type SockclntCreate struct {
+	Context uint32
+	Name    string `vpp:"size=64"`
+}
+
+func (*SockclntCreate) GetMessageName() string {
+	return "sockclnt_create"
+}
+func (*SockclntCreate) GetCrcString() string {
+	return "455fb9c4"
+}
+func (*SockclntCreate) GetMessageType() MessageType {
+	return OtherMessage
+}
+
+// Registration reply carrying the client index and the table of all messages known to VPP
+type SockclntCreateReply struct {
+	ClientIndex  uint32
+	Context      uint32
+	Response     int32
+	Index        uint32
+	Count        uint16
+	MessageTable []MessageTableEntry `vpp:"sizefrom=Count"`
+}
+
+func (*SockclntCreateReply) GetMessageName() string {
+	return "sockclnt_create_reply"
+}
+func (*SockclntCreateReply) GetCrcString() string {
+	return "35166268"
+}
+func (*SockclntCreateReply) GetMessageType() MessageType {
+	return OtherMessage
+}
+
+type MessageTableEntry struct {
+	Index uint16
+	Name  string `vpp:"size=64"`
+}
+
+type SockclntDelete struct {
+	Index uint32
+}
+
+func (*SockclntDelete) GetMessageName() string {
+	return "sockclnt_delete"
+}
+func (*SockclntDelete) GetCrcString() string {
+	return "8ac76db6"
+}
+func (*SockclntDelete) GetMessageType() MessageType {
+	return RequestMessage
+}
+
+type SockclntDeleteReply struct {
+	Response int32
+}
+
+func (*SockclntDeleteReply) GetMessageName() string {
+	return "sockclnt_delete_reply"
+}
+func (*SockclntDeleteReply) GetCrcString() string {
+	return "8f38b1ee"
+}
+func (*SockclntDeleteReply) GetMessageType() MessageType {
+	return ReplyMessage
+}
@@ -0,0 +1,132 @@
+package api
+
+func init() {
+	RegisterMessage(&WantStats{})
+	RegisterMessage(&WantStatsReply{})
+	RegisterMessage(&VnetInterfaceSimpleCounters{})
+	RegisterMessage(&VnetInterfaceCombinedCounters{})
+	RegisterMessage(&VnetIp4FibCounters{})
+	RegisterMessage(&VnetIp6FibCounters{})
+}
+
+// Subscription to periodic counter notifications (VnetInterfaceSimpleCounters etc.)
+type WantStats struct {
+	EnableDisable uint32
+	Pid           uint32
+}
+
+func (*WantStats) GetMessageName() string {
+	return "want_stats"
+}
+func (*WantStats) GetCrcString() string {
+	return "476f5a08"
+}
+func (*WantStats) GetMessageType() MessageType {
+	return RequestMessage
+}
+
+type WantStatsReply struct {
+	Retval int32
+}
+
+func (*WantStatsReply) GetMessageName() string {
+	return "want_stats_reply"
+}
+func (*WantStatsReply) GetCrcString() string {
+	return "e8d4e804"
+}
+func (*WantStatsReply) GetMessageType() MessageType {
+	return ReplyMessage
+}
+
+// Simple interface counters notification, holds packet counts of Count interfaces starting at FirstSwIfIndex
+type VnetInterfaceSimpleCounters struct {
+	VnetCounterType uint8
+	FirstSwIfIndex  uint32
+	Count           uint32
+	Data            []uint64 `vpp:"sizefrom=Count"`
+}
+
+func (*VnetInterfaceSimpleCounters) GetMessageName() string {
+	return "vnet_interface_simple_counters"
+}
+func (*VnetInterfaceSimpleCounters) GetCrcString() string {
+	return "9bc4a808"
+}
+func (*VnetInterfaceSimpleCounters) GetMessageType() MessageType {
+	return OtherMessage
+}
+
+// Packet and byte count of a combined counter
+type VlibCounter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// Combined interface counters notification, holds counters of Count interfaces starting at FirstSwIfIndex
+type VnetInterfaceCombinedCounters struct {
+	VnetCounterType uint8
+	FirstSwIfIndex  uint32
+	Count           uint32
+	Data            []VlibCounter `vpp:"sizefrom=Count"`
+}
+
+func (*VnetInterfaceCombinedCounters) GetMessageName() string {
+	return "vnet_interface_combined_counters"
+}
+func (*VnetInterfaceCombinedCounters) GetCrcString() string {
+	return "2c595002"
+}
+func (*VnetInterfaceCombinedCounters) GetMessageType() MessageType {
+	return OtherMessage
+}
+
+// Counters of a single IPv4 FIB entry
+type Ip4FibCounter struct {
+	Address       []byte `vpp:"size=4"`
+	AddressLength uint8
+	Packets       uint64
+	Bytes         uint64
+}
+
+// IPv4 FIB counters notification, holds counters of Count prefixes of a single VRF
+type VnetIp4FibCounters struct {
+	VrfID    uint32
+	Count    uint32
+	Counters []Ip4FibCounter `vpp:"sizefrom=Count"`
+}
+
+func (*VnetIp4FibCounters) GetMessageName() string {
+	return "vnet_ip4_fib_counters"
+}
+func (*VnetIp4FibCounters) GetCrcString() string {
+	return "57e3feec"
+}
+func (*VnetIp4FibCounters) GetMessageType() MessageType {
+	return OtherMessage
+}
+
+// Counters of a single IPv6 FIB entry
+type Ip6FibCounter struct {
+	Address       []byte `vpp:"size=16"`
+	AddressLength uint8
+	Packets       uint64
+	Bytes         uint64
+}
+
+// IPv6 FIB counters notification, holds counters of Count prefixes of a single VRF
+type VnetIp6FibCounters struct {
+	VrfID    uint32
+	Count    uint32
+	Counters []Ip6FibCounter `vpp:"sizefrom=Count"`
+}
+
+func (*VnetIp6FibCounters) GetMessageName() string {
+	return "vnet_ip6_fib_counters"
+}
+func (*VnetIp6FibCounters) GetCrcString() string {
+	return "25bbc2d0"
+}
+func (*VnetIp6FibCounters) GetMessageType() MessageType {
+	return OtherMessage
+}
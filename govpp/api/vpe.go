@@ -0,0 +1,104 @@
+package api
+
+func init() {
+	RegisterMessage(&ControlPing{})
+	RegisterMessage(&ControlPingReply{})
+	RegisterMessage(&ShowVersion{})
+	RegisterMessage(&ShowVersionReply{})
+	RegisterMessage(&CliInband{})
+	RegisterMessage(&CliInbandReply{})
+}
+
+// Control ping, used as a keepalive and to mark the end of dumps
+type ControlPing struct {
+}
+
+func (*ControlPing) GetMessageName() string {
+	return "control_ping"
+}
+func (*ControlPing) GetCrcString() string {
+	return "51077d14"
+}
+func (*ControlPing) GetMessageType() MessageType {
+	return RequestMessage
+}
+
+type ControlPingReply struct {
+	Retval      int32
+	ClientIndex uint32
+	VpePid      uint32
+}
+
+func (*ControlPingReply) GetMessageName() string {
+	return "control_ping_reply"
+}
+func (*ControlPingReply) GetCrcString() string {
+	return "f6b0b8ca"
+}
+func (*ControlPingReply) GetMessageType() MessageType {
+	return ReplyMessage
+}
+
+// Version of the running VPP
+type ShowVersion struct {
+}
+
+func (*ShowVersion) GetMessageName() string {
+	return "show_version"
+}
+func (*ShowVersion) GetCrcString() string {
+	return "51077d14"
+}
+func (*ShowVersion) GetMessageType() MessageType {
+	return RequestMessage
+}
+
+type ShowVersionReply struct {
+	Retval         int32
+	Program        string `vpp:"size=32"`
+	Version        string `vpp:"size=32"`
+	BuildDate      string `vpp:"size=32"`
+	BuildDirectory string `vpp:"size=256"`
+}
+
+func (*ShowVersionReply) GetMessageName() string {
+	return "show_version_reply"
+}
+func (*ShowVersionReply) GetCrcString() string {
+	return "8b5a13b4"
+}
+func (*ShowVersionReply) GetMessageType() MessageType {
+	return ReplyMessage
+}
+
+// Execute a debug CLI command, its output is returned in CliInbandReply
+type CliInband struct {
+	Length uint32
+	Cmd    []byte `vpp:"sizefrom=Length"`
+}
+
+func (*CliInband) GetMessageName() string {
+	return "cli_inband"
+}
+func (*CliInband) GetCrcString() string {
+	return "b1ad59b3"
+}
+func (*CliInband) GetMessageType() MessageType {
+	return RequestMessage
+}
+
+type CliInbandReply struct {
+	Retval int32
+	Length uint32
+	Reply  []byte `vpp:"sizefrom=Length"`
+}
+
+func (*CliInbandReply) GetMessageName() string {
+	return "cli_inband_reply"
+}
+func (*CliInbandReply) GetCrcString() string {
+	return "6d3c80a4"
+}
+func (*CliInbandReply) GetMessageType() MessageType {
+	return ReplyMessage
+}
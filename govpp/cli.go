@@ -0,0 +1,23 @@
+package govpp
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/govpp/api"
+)
+
+// Blocking execution of a debug CLI command (e.g. show runtime), returns its output
+func (s *VppConnection) Cli(ctx context.Context, cmd string) (string, error) {
+	reply, err := s.SendRequest(ctx, &api.CliInband{Length: uint32(len(cmd)), Cmd: []byte(cmd)})
+	if err != nil {
+		return "", fmt.Errorf("CLI command %q failed: %w", cmd, err)
+	}
+
+	output := string(reply.(*api.CliInbandReply).Reply)
+	log.WithFields(log.Fields{
+		"cmd":    cmd,
+		"length": len(output),
+	}).Debug("CLI command executed successfully")
+	return output, nil
+}
@@ -0,0 +1,82 @@
+package govpp
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/govpp/api"
+	"sort"
+	"strings"
+)
+
+// Compatibility of a message known to the agent with the connected VPP
+type MessageCompatibility string
+
+const (
+	// VPP knows the message with the same CRC
+	Compatible MessageCompatibility = "compatible"
+	// VPP knows the message, but its definition differs
+	CrcMismatch MessageCompatibility = "crc-mismatch"
+	// VPP does not know the message at all
+	Missing MessageCompatibility = "missing"
+)
+
+// Map IDs of all known messages as assigned by VPP to their types. Messages are looked up by name and CRC,
+// so that a message whose definition differs in VPP is never mis-decoded.
+func (s *VppConnection) resolveMessages() {
+	// Names of all messages known to VPP, regardless of their CRC
+	vppMessages := make(map[string]bool)
+	for _, nameWithCrc := range s.transport.GetMsgNames() {
+		if i := strings.LastIndex(nameWithCrc, "_"); i > 0 {
+			vppMessages[nameWithCrc[:i]] = true
+		}
+	}
+
+	s.compatibility = make(map[string]MessageCompatibility)
+	for _, msg := range api.AllMessages() {
+		if msgID, err := s.transport.GetMsgID(msg.GetMessageName(), msg.GetCrcString()); err == nil {
+			s.msgTypes[msgID] = msg
+			s.compatibility[msg.GetMessageName()] = Compatible
+			continue
+		}
+
+		if vppMessages[msg.GetMessageName()] {
+			s.compatibility[msg.GetMessageName()] = CrcMismatch
+		} else {
+			s.compatibility[msg.GetMessageName()] = Missing
+		}
+
+		log.WithFields(log.Fields{
+			"message":       msg.GetMessageName(),
+			"crc":           msg.GetCrcString(),
+			"compatibility": s.compatibility[msg.GetMessageName()],
+		}).Warn("Message not supported by connected VPP")
+	}
+}
+
+// Returns compatibility of all messages known to the agent with connected VPP, keyed by message name
+func (s *VppConnection) Compatibility() map[string]MessageCompatibility {
+	compatibility := make(map[string]MessageCompatibility)
+	for name, status := range s.compatibility {
+		compatibility[name] = status
+	}
+	return compatibility
+}
+
+// Returns an error listing all msgs missing in connected VPP or having a different CRC there
+func (s *VppConnection) CheckCompatibility(msgs ...api.Message) error {
+	var incompatible []string
+	for _, msg := range msgs {
+		if status := s.compatibility[msg.GetMessageName()]; status != Compatible {
+			if status == "" {
+				status = Missing
+			}
+			incompatible = append(incompatible, fmt.Sprintf("%v (%v)", msg.GetMessageName(), status))
+		}
+	}
+
+	if len(incompatible) > 0 {
+		sort.Strings(incompatible)
+		return fmt.Errorf("Incompatible messages: %v", strings.Join(incompatible, ", "))
+	}
+	return nil
+}
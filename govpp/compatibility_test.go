@@ -0,0 +1,119 @@
+package govpp_test
+
+import (
+	"context"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/socketclient"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+func TestCompatibility(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	server.SetMessageCrc(&api.ShowVersionReply{}, "deadbeef")
+	server.RemoveMessage(&api.WantStats{})
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	compatibility := connection.Compatibility()
+	expected := map[string]govpp.MessageCompatibility{
+		"show_version":       govpp.Compatible,
+		"show_version_reply": govpp.CrcMismatch,
+		"want_stats":         govpp.Missing,
+		"control_ping":       govpp.Compatible,
+	}
+	for name, status := range expected {
+		if compatibility[name] != status {
+			t.Errorf("Unexpected compatibility of %v: %v", name, compatibility[name])
+		}
+	}
+
+	if err := connection.CheckCompatibility(&api.ShowVersion{}, &api.ShowVersionReply{}); err == nil {
+		t.Error("Message with mismatched CRC reported as compatible")
+	}
+	if err := connection.CheckCompatibility(&api.SwInterfaceDump{}, &api.SwInterfaceDetails{}); err != nil {
+		t.Errorf("Compatible messages reported as incompatible: %v", err)
+	}
+
+	if _, err := connection.SendRequest(context.Background(), &api.WantStats{}); err == nil {
+		t.Error("Missing message sent to VPP")
+	}
+}
+
+func TestConnectIncompatible(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	server.SetMessageCrc(&api.ControlPing{}, "deadbeef")
+
+	attempt := govpp.VppConnectionAttempt{
+		Name:      "test",
+		Transport: socketclient.NewSocketClient(server.SocketPath()),
+	}
+	if _, err := attempt.TryConnect(context.Background()); err == nil {
+		t.Error("Connected to VPP with incompatible control ping")
+	}
+}
+
+// Transport delivering an interface event as soon as it is connected, before the connection resolved its messages
+type eagerTransport struct {
+	*socketclient.SocketClient
+	callback  func(msgID uint16, data []byte)
+	delivered chan (int)
+}
+
+func (s *eagerTransport) SetMsgCallback(callback func(msgID uint16, data []byte)) {
+	s.callback = callback
+	s.SocketClient.SetMsgCallback(callback)
+}
+
+func (s *eagerTransport) Connect(name string) error {
+	if err := s.SocketClient.Connect(name); err != nil {
+		return err
+	}
+
+	msg := &api.SwInterfaceEvent{SwIfIndex: 1}
+	msgID, _ := s.GetMsgID(msg.GetMessageName(), msg.GetCrcString())
+	data, _ := api.EncodeMessage(msg, msgID, s.ClientIndex(), 0)
+	go func() {
+		s.callback(msgID, data)
+		close(s.delivered)
+	}()
+	return nil
+}
+
+// Messages received while connecting are decoded only once the message table is resolved
+func TestReceiveWhileConnecting(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	transport := &eagerTransport{
+		SocketClient: socketclient.NewSocketClient(server.SocketPath()),
+		delivered:    make(chan (int)),
+	}
+	connection, err := govpp.VppConnectionAttempt{Name: "test", Transport: transport}.TryConnect(context.Background())
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer connection.Disconnect()
+
+	select {
+	case <-transport.delivered:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Early message not processed")
+	}
+}
@@ -0,0 +1,91 @@
+package govpp
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/govpp/api"
+	"sync"
+)
+
+// Handler of a message received from VPP
+type MessageHandler func(msg api.Message, ctx uint)
+
+// Subscription of a handler to messages of a single type received over a connection
+type Subscription struct {
+	dispatcher *dispatcher
+	key        string
+	handler    MessageHandler
+	// Held for reading while the handler runs, so that unsubscribe can wait for it
+	lock         sync.RWMutex
+	unsubscribed bool
+}
+
+// Stop handing messages over to the subscribed handler. Waits for the handler to finish if it is handling a message
+// at the moment, so the handler is guaranteed not to run once Unsubscribe returns. Must not be called from
+// the handler itself.
+func (s *Subscription) Unsubscribe() {
+	s.dispatcher.unsubscribe(s)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.unsubscribed = true
+}
+
+func (s *Subscription) handle(msg api.Message, ctx uint) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.unsubscribed {
+		s.handler(msg, ctx)
+	}
+}
+
+// Owner of all message handlers of a connection, fanning each received message out to all its subscribers
+type dispatcher struct {
+	lock          sync.RWMutex
+	subscriptions map[string]([]*Subscription)
+}
+
+func newDispatcher() *dispatcher {
+	return &dispatcher{subscriptions: make(map[string]([]*Subscription))}
+}
+
+func (s *dispatcher) subscribe(msg api.Message, handler MessageHandler) *Subscription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	subscription := &Subscription{dispatcher: s, key: api.NameWithCrc(msg), handler: handler}
+	s.subscriptions[subscription.key] = append(s.subscriptions[subscription.key], subscription)
+	return subscription
+}
+
+func (s *dispatcher) unsubscribe(subscription *Subscription) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	subscriptions := s.subscriptions[subscription.key]
+	for i, subscribed := range subscriptions {
+		if subscribed == subscription {
+			// Copy, so that a dispatch in progress is not affected
+			remaining := make([]*Subscription, 0, len(subscriptions)-1)
+			remaining = append(remaining, subscriptions[:i]...)
+			s.subscriptions[subscription.key] = append(remaining, subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// Hand msg over to all its subscribers
+func (s *dispatcher) dispatch(msg api.Message, ctx uint) {
+	s.lock.RLock()
+	subscriptions := s.subscriptions[api.NameWithCrc(msg)]
+	s.lock.RUnlock()
+
+	if len(subscriptions) == 0 {
+		log.WithField("message", msg.GetMessageName()).Debug("No subscriber for message, ignoring")
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		subscription.handle(msg, ctx)
+	}
+}
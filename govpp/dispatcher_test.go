@@ -0,0 +1,51 @@
+package govpp_test
+
+import (
+	"context"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+func TestUnsubscribeWaitsForHandler(t *testing.T) {
+	server, connection := vpptest.NewConnectedServer(t)
+
+	started := make(chan (int))
+	release := make(chan (int))
+	subscription := connection.Subscribe(&api.SwInterfaceEvent{}, func(api.Message, uint) {
+		close(started)
+		<-release
+	})
+
+	request := &api.WantInterfaceEvents{EnableDisable: 1, Pid: uint32(connection.GetPid())}
+	if _, err := connection.SendRequest(context.Background(), request); err != nil {
+		t.Fatalf("Unable to enable interface events: %v", err)
+	}
+	server.InjectInterfaceEvent(1, true, true, false)
+
+	select {
+	case <-started:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Handler not invoked")
+	}
+
+	unsubscribed := make(chan (int))
+	go func() {
+		subscription.Unsubscribe()
+		close(unsubscribed)
+	}()
+
+	select {
+	case <-unsubscribed:
+		t.Fatal("Unsubscribe returned while the handler was running")
+	case <-time.After(time.Millisecond * time.Duration(100)):
+	}
+
+	close(release)
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Unsubscribe did not return after the handler finished")
+	}
+}
@@ -1,70 +1,100 @@
-package govpp
-
 /*
-#cgo LDFLAGS: -L/usr/lib/x86_64-linux-gnu -lvlibmemoryclient -lvlibapi -lsvm -lvppinfra -lpthread -lm -lrt -lpneum
-
-#include <stdlib.h>
-
-#include <vpp-api/pneum/pneum.h>
-#include <vnet/vnet.h>
-#include <vlib/vlib.h>
-#include <vlib/unix/unix.h>
-#include <vlibapi/api.h>
-#include <vlibmemory/api.h>
-
-#include <vpp/api/vpe_msg_enum.h>
-
-#define vl_typedefs
-#include <vpp/api/vpe_all_api_h.h>
-#undef vl_typedefs
-
-#define vl_endianfun
-#include <vpp/api/vpe_all_api_h.h>
-#undef vl_endianfun
+Package govpp provides a connection to VPP binary APIs on top of a pluggable transport.
 */
-import "C"
+package govpp
+
 import (
+	"context"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
-	"reflect"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/socketclient"
 	"sync"
-	"unsafe"
 )
 
+// Transport exchanging binary messages with VPP
+type Transport interface {
+	Connect(name string) error
+	Disconnect() error
+	SendMsg(data []byte) error
+	SetMsgCallback(callback func(msgID uint16, data []byte))
+	GetMsgID(msgName string, msgCrc string) (uint16, error)
+	GetMsgNames() []string
+	ClientIndex() uint32
+}
+
 type VppConnectionAttempt struct {
+	// Client name the agent registers under in VPP
 	Name string
+	// Path to VPP API socket (as configured by socksvr { socket-name } in VPP), default socket is used if empty
+	SocketPath string
+	// Depth of the queue of received messages, default queue size is used if 0
+	QueueSize int
+	// Optional, socket client connecting to SocketPath is used if not set
+	Transport Transport
+	// Optional, metrics shared by all connections made with this attempt so that they survive reconnects.
+	// Each connection starts with new metrics if not set.
+	Metrics *ApiMetrics
 }
 
-func (attempt VppConnectionAttempt) Connect() *VppConnection {
+// Connect to VPP, panics if the connection cannot be established
+func (attempt VppConnectionAttempt) Connect(ctx context.Context) *VppConnection {
+	connection, err := attempt.TryConnect(ctx)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Panic("Unable to connect to VPP APIs")
+	}
+	return connection
+}
+
+// Connect to VPP, returns an error if the connection cannot be established
+func (attempt VppConnectionAttempt) TryConnect(ctx context.Context) (*VppConnection, error) {
 	log.WithFields(log.Fields{
 		"configuration": attempt,
 	}).Debug("Attempting connect to VPP APIs")
 
-	// FIXME add a watchdog goroutine to time out connection (and try infinitely) ... it's infinite
-
-	cs := C.CString("/vpe-api")
-	defer C.free(unsafe.Pointer(cs))
-	csName := C.CString(attempt.Name)
-	defer C.free(unsafe.Pointer(csName))
-
-	if rv := C.vl_client_connect_to_vlib(cs, csName, 32); rv < 0 {
-		log.WithFields(log.Fields{
-			"return": rv,
-		}).Panic("Unable to open a connection to VPP APIs")
+	transport := attempt.Transport
+	if transport == nil {
+		socketClient := socketclient.NewSocketClient(attempt.SocketPath)
+		socketClient.SetQueueSize(attempt.QueueSize)
+		transport = socketClient
 	}
 
-	apiMain := reflect.ValueOf(C.api_main)
-	shmemHdr := apiMain.FieldByName("shmem_hdr")
-	queue := reflect.Indirect(shmemHdr).FieldByName("vl_input_queue")
+	metrics := attempt.Metrics
+	if metrics == nil {
+		metrics = NewApiMetrics()
+	}
 
 	connection := VppConnection{
-		apiMain:     apiMain,
-		queue:       unsafe.Pointer(reflect.Indirect(queue).UnsafeAddr()),
-		ClientIndex: uint(apiMain.FieldByName("my_client_index").Int()),
-		contextId:   0,
+		transport:  transport,
+		contextId:  0,
+		msgTypes:   make(map[uint16]api.Message),
+		resolved:   make(chan (int)),
+		requests:   make(map[uint]*pendingRequest),
+		dispatcher: newDispatcher(),
+		metrics:    metrics,
 	}
+	transport.SetMsgCallback(connection.receive)
 
-	connection.Pid, _ = controlPingSync(&connection, 0)
+	if err := transport.Connect(attempt.Name); err != nil {
+		return nil, fmt.Errorf("Unable to open a connection to VPP APIs: %v", err)
+	}
+	connection.ClientIndex = uint(transport.ClientIndex())
+	// Message table is known only once connected, while the transport may deliver messages right away
+	connection.resolveMessages()
+	close(connection.resolved)
+	if err := connection.CheckCompatibility(&api.ControlPing{}, &api.ControlPingReply{}); err != nil {
+		transport.Disconnect()
+		return nil, fmt.Errorf("Incompatible VPP: %v", err)
+	}
+
+	pid, err := connection.Ping(ctx)
+	if err != nil {
+		transport.Disconnect()
+		return nil, fmt.Errorf("Unable to invoke initial ping: %v", err)
+	}
+	connection.Pid = pid
 
 	log.WithFields(log.Fields{
 		"pid": connection.Pid,
@@ -74,44 +104,83 @@ func (attempt VppConnectionAttempt) Connect() *VppConnection {
 		"connection": connection.String(),
 	}).Info("Successfully connected to VPP APIs")
 
-	return &connection
+	return &connection, nil
 }
 
 type VppConnection struct {
 	ClientIndex uint
 	Pid         uint
 	Lock        sync.Mutex
-	apiMain     reflect.Value
-	queue       unsafe.Pointer
+	transport   Transport
 	contextId   uint
+	msgTypes    map[uint16]api.Message
+	// Closed once msgTypes and compatibility are filled, received messages wait for it
+	resolved chan (int)
+	// Compatibility of all messages known to the agent, keyed by message name
+	compatibility map[string]MessageCompatibility
+	requestsLock  sync.Mutex
+	requests      map[uint]*pendingRequest
+	dispatcher    *dispatcher
+	metrics       *ApiMetrics
 }
 
 func (s *VppConnection) String() string {
-	return fmt.Sprintf("{VPP API CONNECTION: Pid:%v, ClientId: %v, Queue:%v, ClientIndex:%v}",
-		s.Pid, s.ClientIndex, s.queue, s.ClientIndex)
+	return s.Locked(func() interface{} {
+		return fmt.Sprintf("{VPP API CONNECTION: Pid:%v, ClientIndex:%v, Transport:%T}",
+			s.Pid, s.ClientIndex, s.transport)
+	}).(string)
+}
+
+// Pid of connected VPP, 0 once disconnected. Pid changes if VPP restarts while connected.
+func (s *VppConnection) GetPid() uint {
+	return s.Locked(func() interface{} {
+		return s.Pid
+	}).(uint)
+}
+
+// Record a new pid of connected VPP e.g. after VPP restarted
+func (s *VppConnection) SetPid(pid uint) {
+	s.Locked(func() interface{} {
+		s.Pid = pid
+		return nil
+	})
 }
 
 func (s *VppConnection) Disconnect() {
 	log.Debug("Attempting disconnecting from VPP APIs")
 
-	C.vl_client_disconnect_from_vlib()
-	s.ClientIndex = 0
-	s.Pid = 0
-	s.Lock = sync.Mutex{}
-	s.apiMain = reflect.ValueOf(nil)
-	s.queue = nil
-	s.contextId = 0
+	if err := s.transport.Disconnect(); err != nil {
+		log.WithField("error", err).Warn("Unable to disconnect cleanly from VPP APIs")
+	}
+	s.Locked(func() interface{} {
+		s.ClientIndex = 0
+		s.Pid = 0
+		s.contextId = 0
+		return nil
+	})
 
 	log.Debug("VPP APIs disconnected successfully")
 }
 
-func (s *VppConnection) SendMessage(msg unsafe.Pointer) {
-	C.vl_msg_api_send_shmem((*C.struct__unix_shared_memory_queue)(s.queue), (*C.u8)(unsafe.Pointer(&msg)))
-}
+// Encode and send a message to VPP under provided context
+func (s *VppConnection) SendMessage(msg api.Message, ctx uint) error {
+	msgID, err := s.transport.GetMsgID(msg.GetMessageName(), msg.GetCrcString())
+	if err != nil {
+		return err
+	}
+
+	clientIndex := s.Locked(func() interface{} { return s.ClientIndex }).(uint)
+	data, err := api.EncodeMessage(msg, msgID, uint32(clientIndex), uint32(ctx))
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"message": msg.GetMessageName(),
+		"ctx":     ctx,
+	}).Debug("Sending message")
 
-// Blocking invocation of a control ping
-func (s *VppConnection) Ping(ctx uint, callback func(pid uint, ctx uint)) {
-	controlPing(s, ctx, callback)
+	return s.transport.SendMsg(data)
 }
 
 func (s *VppConnection) NextContextId() uint {
@@ -132,3 +201,36 @@ func (s *VppConnection) Locked(lambda func() interface{}) interface{} {
 
 	return lambda()
 }
+
+// Decode a received message and hand it over to a request waiting for it or to its subscribers
+func (s *VppConnection) receive(msgID uint16, data []byte) {
+	<-s.resolved
+	prototype, isKnown := s.msgTypes[msgID]
+	if !isKnown {
+		log.WithField("msg-id", msgID).Debug("Unknown message, ignoring")
+		return
+	}
+
+	msg := api.NewMessage(prototype)
+	if err := api.DecodeMessage(data, msg); err != nil {
+		log.WithFields(log.Fields{
+			"message": msg.GetMessageName(),
+			"error":   err,
+		}).Error("Unable to decode message, ignoring")
+		return
+	}
+
+	ctx, _ := api.DecodeContext(data, msg.GetMessageType())
+
+	if msg.GetMessageType() == api.ReplyMessage && s.deliverReply(uint(ctx), msg) {
+		return
+	}
+
+	s.dispatcher.dispatch(msg, uint(ctx))
+}
+
+// Subscribe handler to all messages of the same type as msg received over this connection. Any number of
+// handlers can subscribe to the same message type.
+func (s *VppConnection) Subscribe(msg api.Message, handler MessageHandler) *Subscription {
+	return s.dispatcher.subscribe(msg, handler)
+}
@@ -0,0 +1,126 @@
+package govpp
+
+import (
+	"context"
+	"pnda/vpp/monitoring/govpp/api"
+	"sync"
+	"time"
+)
+
+// Upper bounds of reply latency histogram buckets, latencies above the last bound fall into an overflow bucket
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	time.Millisecond * 5,
+	time.Millisecond * 10,
+	time.Millisecond * 50,
+	time.Millisecond * 100,
+	time.Millisecond * 500,
+	time.Second,
+	time.Second * 5,
+}
+
+const overflowBucket = "+Inf"
+
+// Metrics of requests of a single message type sent over a connection
+type MessageMetrics struct {
+	Requests uint64 `json:"requests"`
+	Replies  uint64 `json:"replies"`
+	Timeouts uint64 `json:"timeouts"`
+	// Replies with a negative retval
+	Errors uint64 `json:"errors"`
+	// Number of replies per latency bucket, keyed by bucket upper bound e.g. 5ms
+	LatencyHistogram map[string]uint64 `json:"latency_histogram"`
+	TotalLatencyMs   float64           `json:"total_latency_ms"`
+}
+
+// Per request message type metrics. Shared by all connections made with the same VppConnectionAttempt, so that
+// metrics of a VPP target survive reconnects.
+type ApiMetrics struct {
+	lock     sync.Mutex
+	since    time.Time
+	messages map[string]*MessageMetrics
+}
+
+func NewApiMetrics() *ApiMetrics {
+	return &ApiMetrics{since: time.Now(), messages: make(map[string]*MessageMetrics)}
+}
+
+func (s *ApiMetrics) get(request api.Message) *MessageMetrics {
+	metrics, isPresent := s.messages[request.GetMessageName()]
+	if !isPresent {
+		metrics = &MessageMetrics{LatencyHistogram: make(map[string]uint64)}
+		s.messages[request.GetMessageName()] = metrics
+	}
+	return metrics
+}
+
+func (s *ApiMetrics) requestSent(request api.Message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.get(request).Requests++
+}
+
+func (s *ApiMetrics) replyReceived(request api.Message, reply api.Message, latency time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	metrics := s.get(request)
+	metrics.Replies++
+	metrics.TotalLatencyMs += float64(latency) / float64(time.Millisecond)
+	metrics.LatencyHistogram[latencyBucket(latency)]++
+
+	if api.RetvalError(request, reply) != nil {
+		metrics.Errors++
+	}
+}
+
+// Record a request that failed with err, only timeouts are counted
+func (s *ApiMetrics) requestFailed(request api.Message, err error) {
+	if err != context.DeadlineExceeded {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.get(request).Timeouts++
+}
+
+func (s *ApiMetrics) snapshot() map[string]MessageMetrics {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshot := make(map[string]MessageMetrics)
+	for name, metrics := range s.messages {
+		histogram := make(map[string]uint64)
+		for bucket, count := range metrics.LatencyHistogram {
+			histogram[bucket] = count
+		}
+
+		copied := *metrics
+		copied.LatencyHistogram = histogram
+		snapshot[name] = copied
+	}
+	return snapshot
+}
+
+func latencyBucket(latency time.Duration) string {
+	for _, bound := range LatencyBuckets {
+		if latency <= bound {
+			return bound.String()
+		}
+	}
+	return overflowBucket
+}
+
+// Returns metrics of all requests sent so far, keyed by request message name. Includes requests sent over previous
+// connections made with the same metrics, see MetricsSince.
+func (s *VppConnection) Metrics() map[string]MessageMetrics {
+	return s.metrics.snapshot()
+}
+
+// Time metrics of this connection started to be recorded, before this connection was made if they are shared
+func (s *VppConnection) MetricsSince() time.Time {
+	return s.metrics.since
+}
@@ -0,0 +1,180 @@
+package govpp
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/govpp/api"
+	"time"
+)
+
+// Timeout applied to requests whose context has no deadline
+const DefaultReplyTimeout = time.Second * 5
+
+// Request waiting for its reply (or replies) identified by context ID
+type pendingRequest struct {
+	replies chan (api.Message)
+	done    chan (int)
+}
+
+// Send a request and wait for its reply. Reply is matched by a context ID owned by the connection.
+// A reply with a negative retval is returned together with an api.VppApiError.
+func (s *VppConnection) SendRequest(ctx context.Context, request api.Message) (api.Message, error) {
+	expectedReply, err := api.NewReply(request)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	ctxId, pending := s.newPendingRequest()
+	defer s.removePendingRequest(ctxId)
+
+	start := time.Now()
+	if err := s.SendMessage(request, ctxId); err != nil {
+		return nil, err
+	}
+	s.metrics.requestSent(request)
+
+	select {
+	case reply := <-pending.replies:
+		if reply.GetMessageName() != expectedReply.GetMessageName() {
+			return nil, fmt.Errorf("Unexpected reply %v to request %v", reply.GetMessageName(), request.GetMessageName())
+		}
+		s.metrics.replyReceived(request, reply, time.Since(start))
+		// Reply is returned together with the error, so that the caller can inspect it
+		return reply, api.RetvalError(request, reply)
+	case <-ctx.Done():
+		s.metrics.requestFailed(request, ctx.Err())
+		return nil, fmt.Errorf("No reply received for %v (ctx %v): %w", request.GetMessageName(), ctxId, ctx.Err())
+	}
+}
+
+// Send a dump request, followed by a control ping with the same context ID. The control ping reply marks
+// the end of the dump. Replies have to be consumed using the returned iterator.
+func (s *VppConnection) SendMultiRequest(ctx context.Context, request api.Message) *ReplyIterator {
+	ctx, cancel := withDefaultTimeout(ctx)
+
+	ctxId, pending := s.newPendingRequest()
+	iterator := &ReplyIterator{
+		connection: s,
+		request:    request,
+		ctx:        ctx,
+		cancel:     cancel,
+		ctxId:      ctxId,
+		pending:    pending,
+		start:      time.Now(),
+	}
+
+	if err := s.SendMessage(request, ctxId); err != nil {
+		iterator.finish(err)
+		return iterator
+	}
+	s.metrics.requestSent(request)
+	if err := s.SendMessage(&api.ControlPing{}, ctxId); err != nil {
+		iterator.finish(err)
+	}
+
+	return iterator
+}
+
+// Iterator over replies to a dump request
+type ReplyIterator struct {
+	connection *VppConnection
+	request    api.Message
+	ctx        context.Context
+	cancel     context.CancelFunc
+	ctxId      uint
+	pending    *pendingRequest
+	start      time.Time
+	finished   bool
+	err        error
+}
+
+// Returns next reply or nil once all replies were received. Returns an error if the request failed or timed out.
+func (s *ReplyIterator) Next() (api.Message, error) {
+	if s.finished {
+		return nil, s.err
+	}
+
+	select {
+	case reply := <-s.pending.replies:
+		if _, isEnd := reply.(*api.ControlPingReply); isEnd {
+			s.connection.metrics.replyReceived(s.request, reply, time.Since(s.start))
+			s.finish(nil)
+			return nil, nil
+		}
+		return reply, nil
+	case <-s.ctx.Done():
+		s.connection.metrics.requestFailed(s.request, s.ctx.Err())
+		s.finish(fmt.Errorf("Dump %v (ctx %v) not finished: %w", s.request.GetMessageName(), s.ctxId, s.ctx.Err()))
+		return nil, s.err
+	}
+}
+
+// Abandon remaining replies
+func (s *ReplyIterator) Close() {
+	if !s.finished {
+		s.finish(nil)
+	}
+}
+
+func (s *ReplyIterator) finish(err error) {
+	s.finished = true
+	s.err = err
+	s.cancel()
+	s.connection.removePendingRequest(s.ctxId)
+}
+
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, hasDeadline := ctx.Deadline(); hasDeadline {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultReplyTimeout)
+}
+
+func (s *VppConnection) newPendingRequest() (uint, *pendingRequest) {
+	ctxId := s.NextContextId()
+	pending := &pendingRequest{
+		replies: make(chan (api.Message), 16),
+		done:    make(chan (int)),
+	}
+
+	s.requestsLock.Lock()
+	defer s.requestsLock.Unlock()
+	s.requests[ctxId] = pending
+
+	return ctxId, pending
+}
+
+func (s *VppConnection) removePendingRequest(ctxId uint) {
+	s.requestsLock.Lock()
+	defer s.requestsLock.Unlock()
+
+	if pending, isPresent := s.requests[ctxId]; isPresent {
+		close(pending.done)
+		delete(s.requests, ctxId)
+	}
+}
+
+// Hand a reply over to a pending request, returns false if no request is waiting under ctxId
+func (s *VppConnection) deliverReply(ctxId uint, reply api.Message) bool {
+	s.requestsLock.Lock()
+	pending, isPresent := s.requests[ctxId]
+	s.requestsLock.Unlock()
+
+	if !isPresent {
+		return false
+	}
+
+	select {
+	case pending.replies <- reply:
+	case <-pending.done:
+		log.WithFields(log.Fields{
+			"message": reply.GetMessageName(),
+			"ctx":     ctxId,
+		}).Debug("Request no longer pending, dropping reply")
+	}
+	return true
+}
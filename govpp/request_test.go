@@ -0,0 +1,123 @@
+package govpp_test
+
+import (
+	"context"
+	"github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	logrus.SetLevel(logrus.ErrorLevel)
+}
+
+func TestSendRequest(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	reply, err := connection.SendRequest(context.Background(), &api.ShowVersion{})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if version := reply.(*api.ShowVersionReply); version.Program != "vpe" {
+		t.Errorf("Unexpected reply: %v", version)
+	}
+}
+
+func TestSendRequestTimeout(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+	server.RegisterHandler(&api.ShowVersion{}, func(uint32, api.Message) []api.Message {
+		return nil
+	})
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(100))
+	defer cancel()
+
+	if _, err := connection.SendRequest(ctx, &api.ShowVersion{}); err == nil {
+		t.Error("Request without a reply succeeded")
+	}
+}
+
+func TestSendMultiRequest(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+	server.SetInterfaces(
+		api.SwInterfaceDetails{SwIfIndex: 0, InterfaceName: "local0"},
+		api.SwInterfaceDetails{SwIfIndex: 1, InterfaceName: "GigabitEthernet0/8/0"},
+		api.SwInterfaceDetails{SwIfIndex: 2, InterfaceName: "GigabitEthernet0/9/0"})
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	// Run two dumps concurrently to verify replies are correlated by context
+	first := connection.SendMultiRequest(context.Background(), &api.SwInterfaceDump{})
+	second := connection.SendMultiRequest(context.Background(), &api.SwInterfaceDump{})
+
+	for _, replies := range []*govpp.ReplyIterator{first, second} {
+		var names []string
+		for {
+			reply, err := replies.Next()
+			if err != nil {
+				t.Fatalf("Dump failed: %v", err)
+			}
+			if reply == nil {
+				break
+			}
+			names = append(names, reply.(*api.SwInterfaceDetails).InterfaceName)
+		}
+
+		if len(names) != 3 || names[0] != "local0" || names[2] != "GigabitEthernet0/9/0" {
+			t.Errorf("Unexpected dump result: %v", names)
+		}
+	}
+}
+
+func TestSendMultiRequestTimeout(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	// The end of dump is never confirmed
+	server.RegisterHandler(&api.ControlPing{}, func(uint32, api.Message) []api.Message {
+		return nil
+	})
+	server.SetInterfaces(api.SwInterfaceDetails{SwIfIndex: 0, InterfaceName: "local0"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(100))
+	defer cancel()
+	replies := connection.SendMultiRequest(ctx, &api.SwInterfaceDump{})
+	defer replies.Close()
+
+	if reply, err := replies.Next(); err != nil || reply == nil {
+		t.Fatalf("Expected interface details, received: %v, error: %v", reply, err)
+	}
+
+	if _, err := replies.Next(); err == nil {
+		t.Error("Unfinished dump succeeded")
+	}
+}
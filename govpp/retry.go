@@ -0,0 +1,104 @@
+package govpp
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"math/rand"
+	"time"
+)
+
+// Policy for retrying failed connection attempts with exponential backoff
+type RetryPolicy struct {
+	// Maximum number of connection attempts, 0 means retrying infinitely
+	MaxAttempts uint
+	// Delay after the first failed attempt, doubled after each subsequent failure. Has to be >0.
+	InitialBackoff time.Duration
+	// Upper bound of the delay between attempts, 0 means unbounded (up to MAX_BACKOFF_DOUBLINGS doublings)
+	MaxBackoff time.Duration
+	// Random deviation of each delay as a fraction of the delay e.g. 0.2 for +-20%
+	Jitter float64
+}
+
+// Retry infinitely, starting at 1 second and backing off up to 1 minute
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    0,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Jitter:         0.2,
+}
+
+// Number of doublings after which the delay stops growing even if MaxBackoff is unbounded
+const MAX_BACKOFF_DOUBLINGS = 16
+
+// Returns an error if the policy would retry without any delay or with a bound below the initial delay
+func (s RetryPolicy) Validate() error {
+	if s.InitialBackoff <= 0 {
+		return fmt.Errorf("Initial backoff has to be >0, is: %v", s.InitialBackoff)
+	}
+	if s.MaxBackoff < 0 || (s.MaxBackoff > 0 && s.MaxBackoff < s.InitialBackoff) {
+		return fmt.Errorf("Max backoff has to be 0 (unbounded) or at least initial backoff %v, is: %v",
+			s.InitialBackoff, s.MaxBackoff)
+	}
+	if s.Jitter < 0 || s.Jitter >= 1 {
+		return fmt.Errorf("Jitter has to be within [0, 1), is: %v", s.Jitter)
+	}
+	return nil
+}
+
+// Delay before the next attempt after attempt (starting at 1) failed
+func (s RetryPolicy) Backoff(attempt uint) time.Duration {
+	backoff := s.InitialBackoff
+	for i := uint(1); i < attempt && i <= MAX_BACKOFF_DOUBLINGS && (s.MaxBackoff == 0 || backoff < s.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if s.MaxBackoff > 0 && backoff > s.MaxBackoff {
+		backoff = s.MaxBackoff
+	}
+
+	if s.Jitter > 0 {
+		backoff += time.Duration((rand.Float64()*2 - 1) * s.Jitter * float64(backoff))
+	}
+	return backoff
+}
+
+// Invoked after each failed connection attempt
+type ConnectFailureCallback func(attempt uint, err error)
+
+// Connect to VPP, retrying failed attempts according to policy. onFailure (optional) is invoked after each
+// failed attempt. Returns an error once all attempts failed, ctx was cancelled or if the policy is invalid.
+func (attempt VppConnectionAttempt) ConnectWithRetry(ctx context.Context, policy RetryPolicy,
+	onFailure ConnectFailureCallback) (*VppConnection, error) {
+
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid retry policy: %v", err)
+	}
+
+	for attemptNumber := uint(1); ; attemptNumber++ {
+		connection, err := attempt.TryConnect(ctx)
+		if err == nil {
+			return connection, nil
+		}
+
+		if onFailure != nil {
+			onFailure(attemptNumber, err)
+		}
+
+		if policy.MaxAttempts != 0 && attemptNumber >= policy.MaxAttempts {
+			return nil, fmt.Errorf("Unable to connect to VPP after %v attempts: %v", attemptNumber, err)
+		}
+
+		backoff := policy.Backoff(attemptNumber)
+		log.WithFields(log.Fields{
+			"attempt": attemptNumber,
+			"error":   err,
+			"backoff": backoff,
+		}).Warn("Unable to connect to VPP, retrying")
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("Connecting to VPP cancelled after %v attempts: %v", attemptNumber, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package govpp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/socketclient"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = govpp.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond * 10,
+	MaxBackoff:     time.Millisecond * 50,
+}
+
+func TestBackoff(t *testing.T) {
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for i, backoff := range expected {
+		if actual := testRetryPolicy.Backoff(uint(i + 1)); actual != backoff*time.Millisecond {
+			t.Errorf("Unexpected backoff for attempt %v: %v", i+1, actual)
+		}
+	}
+
+	jittered := testRetryPolicy
+	jittered.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if actual := jittered.Backoff(1); actual < time.Millisecond*5 || actual > time.Millisecond*15 {
+			t.Fatalf("Jittered backoff out of bounds: %v", actual)
+		}
+	}
+}
+
+func TestBackoffUnbounded(t *testing.T) {
+	unbounded := govpp.RetryPolicy{InitialBackoff: time.Millisecond}
+	if actual := unbounded.Backoff(4); actual != time.Millisecond*8 {
+		t.Errorf("Unbounded backoff not doubled: %v", actual)
+	}
+	if actual := unbounded.Backoff(1000); actual != time.Millisecond<<govpp.MAX_BACKOFF_DOUBLINGS {
+		t.Errorf("Unbounded backoff not capped: %v", actual)
+	}
+}
+
+func TestInvalidRetryPolicy(t *testing.T) {
+	invalid := []govpp.RetryPolicy{
+		{},
+		{InitialBackoff: time.Second, MaxBackoff: time.Millisecond},
+		{InitialBackoff: time.Second, Jitter: 1},
+	}
+	for _, policy := range invalid {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Invalid policy accepted: %+v", policy)
+		}
+	}
+
+	if err := govpp.DefaultRetryPolicy.Validate(); err != nil {
+		t.Errorf("Default policy rejected: %v", err)
+	}
+}
+
+func TestConnectWithRetryGivesUp(t *testing.T) {
+	var failures uint
+	attempt := govpp.VppConnectionAttempt{
+		Name:      "test",
+		Transport: socketclient.NewSocketClient("/nonexistent/vpp-api.sock"),
+	}
+
+	_, err := attempt.ConnectWithRetry(context.Background(), testRetryPolicy, func(attempt uint, _ error) {
+		failures = attempt
+	})
+	if err == nil {
+		t.Fatal("Connected to a nonexistent VPP")
+	}
+	if failures != testRetryPolicy.MaxAttempts {
+		t.Errorf("Unexpected number of failed attempts: %v", failures)
+	}
+}
+
+func TestConnectWithRetryWaitsForVpp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vpp-api.sock")
+
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	attempt := govpp.VppConnectionAttempt{
+		Name:      "test",
+		Transport: socketclient.NewSocketClient(path),
+	}
+
+	// VPP API socket appears after the second failed attempt
+	policy := testRetryPolicy
+	policy.MaxAttempts = 0
+	connection, err := attempt.ConnectWithRetry(context.Background(), policy, func(attempt uint, _ error) {
+		if attempt == 2 {
+			os.Symlink(server.SocketPath(), path)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer connection.Disconnect()
+
+	if connection.GetPid() != vpptest.DefaultPid {
+		t.Errorf("Unexpected VPP pid: %v", connection.GetPid())
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Package socketclient implements a pure Go transport for VPP binary APIs using the VPP API unix socket.
+*/
+package socketclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"net"
+	"pnda/vpp/monitoring/govpp/api"
+	"sync"
+	"time"
+)
+
+const DefaultSocketPath = "/run/vpp-api.sock"
+
+// Number of received messages buffered before reading from the socket blocks
+const DefaultQueueSize = 32
+
+// Message ID of sockclnt_create is fixed, since message table is unknown before registration
+const SockclntCreateMsgID = 15
+
+// Size of a header preceding each message on the socket: u64 queue (unused), u32 message length, u32 gc mark
+const HeaderSize = 16
+
+// Largest message accepted from the socket, a corrupt header must not make the reader allocate gigabytes
+const MaxMessageSize = 16 * 1024 * 1024
+
+const connectTimeout = time.Second * 3
+
+// Transport talking to VPP over its API socket
+type SocketClient struct {
+	path        string
+	conn        net.Conn
+	writeLock   sync.Mutex
+	clientIndex uint32
+	msgTable    map[string]uint16
+	callback    func(msgID uint16, data []byte)
+	queueSize   int
+	closed      chan (int)
+}
+
+func NewSocketClient(path string) *SocketClient {
+	if path == "" {
+		path = DefaultSocketPath
+	}
+
+	return &SocketClient{
+		path:      path,
+		msgTable:  make(map[string]uint16),
+		queueSize: DefaultQueueSize,
+	}
+}
+
+// Set the depth of the queue between reading messages from the socket and handing them over to the callback.
+// Has to be set before Connect.
+func (s *SocketClient) SetQueueSize(size int) {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	s.queueSize = size
+}
+
+// Connect to VPP API socket and register as a client under name
+func (s *SocketClient) Connect(name string) error {
+	conn, err := net.DialTimeout("unix", s.path, connectTimeout)
+	if err != nil {
+		return fmt.Errorf("Unable to connect to VPP API socket %v: %v", s.path, err)
+	}
+	s.conn = conn
+
+	if err := s.register(name); err != nil {
+		conn.Close()
+		s.conn = nil
+		return err
+	}
+
+	s.closed = make(chan (int))
+	queue := make(chan ([]byte), s.queueSize)
+	go s.receive(conn, s.closed, queue)
+	go s.dispatch(queue)
+
+	log.WithFields(log.Fields{
+		"socket":       s.path,
+		"client-index": s.clientIndex,
+		"messages":     len(s.msgTable),
+	}).Debug("Connected to VPP API socket")
+
+	return nil
+}
+
+func (s *SocketClient) register(name string) error {
+	data, err := api.EncodeMessage(&api.SockclntCreate{Name: name}, SockclntCreateMsgID, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteMsg(s.conn, data); err != nil {
+		return fmt.Errorf("Unable to send client registration: %v", err)
+	}
+
+	s.conn.SetReadDeadline(time.Now().Add(connectTimeout))
+	defer s.conn.SetReadDeadline(time.Time{})
+
+	replyData, err := ReadMsg(s.conn)
+	if err != nil {
+		return fmt.Errorf("Unable to receive client registration reply: %v", err)
+	}
+
+	reply := &api.SockclntCreateReply{}
+	if err := api.DecodeMessage(replyData, reply); err != nil {
+		return err
+	}
+
+	if reply.Response < 0 {
+		return fmt.Errorf("Client registration refused by VPP, response: %v", reply.Response)
+	}
+
+	s.clientIndex = reply.Index
+	for _, entry := range reply.MessageTable {
+		s.msgTable[entry.Name] = entry.Index
+	}
+
+	return nil
+}
+
+// Unregister the client and close the socket. Repeated (or concurrent) calls are no-ops.
+func (s *SocketClient) Disconnect() error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	if msgID, err := s.GetMsgID((&api.SockclntDelete{}).GetMessageName(), (&api.SockclntDelete{}).GetCrcString()); err == nil {
+		if data, err := api.EncodeMessage(&api.SockclntDelete{Index: s.clientIndex}, msgID, s.clientIndex, 0); err == nil {
+			WriteMsg(s.conn, data)
+		}
+	}
+
+	close(s.closed)
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SocketClient) SendMsg(data []byte) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	if s.conn == nil {
+		return fmt.Errorf("Not connected to VPP API socket")
+	}
+
+	return WriteMsg(s.conn, data)
+}
+
+// Set a callback invoked (from a single receiving goroutine) for each message received from VPP
+func (s *SocketClient) SetMsgCallback(callback func(msgID uint16, data []byte)) {
+	s.callback = callback
+}
+
+// Look up message ID in the message table received from VPP during registration
+func (s *SocketClient) GetMsgID(msgName string, msgCrc string) (uint16, error) {
+	if msgID, isPresent := s.msgTable[msgName+"_"+msgCrc]; isPresent {
+		return msgID, nil
+	}
+	return 0, fmt.Errorf("Unknown message: %v_%v", msgName, msgCrc)
+}
+
+// Returns name_crc of all messages in the message table received from VPP during registration
+func (s *SocketClient) GetMsgNames() []string {
+	var names []string
+	for name := range s.msgTable {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *SocketClient) ClientIndex() uint32 {
+	return s.clientIndex
+}
+
+// Read messages from the socket into queue until the socket is closed
+func (s *SocketClient) receive(conn net.Conn, closed chan (int), queue chan ([]byte)) {
+	defer close(queue)
+
+	for {
+		data, err := ReadMsg(conn)
+		if err != nil {
+			select {
+			case <-closed:
+				// Disconnected on purpose
+			default:
+				log.WithFields(log.Fields{
+					"socket": s.path,
+					"error":  err,
+				}).Error("Unable to read from VPP API socket, stopping")
+			}
+			return
+		}
+
+		queue <- data
+	}
+}
+
+// Hand queued messages over to the callback
+func (s *SocketClient) dispatch(queue chan ([]byte)) {
+	for data := range queue {
+		msgID, err := api.DecodeMessageID(data)
+		if err != nil {
+			log.WithField("error", err).Warn("Ignoring invalid message")
+			continue
+		}
+
+		if s.callback != nil {
+			s.callback(msgID, data)
+		}
+	}
+}
+
+// Write a message prefixed with a header to the socket
+func WriteMsg(writer io.Writer, data []byte) error {
+	buf := make([]byte, HeaderSize+len(data))
+	binary.BigEndian.PutUint32(buf[8:12], uint32(len(data)))
+	copy(buf[HeaderSize:], data)
+
+	_, err := writer.Write(buf)
+	return err
+}
+
+// Read a single message from the socket, stripping its header
+func ReadMsg(reader io.Reader) ([]byte, error) {
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[8:12])
+	if length > MaxMessageSize {
+		return nil, fmt.Errorf("Message of %d bytes exceeds maximum message size %d", length, MaxMessageSize)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
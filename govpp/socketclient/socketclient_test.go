@@ -0,0 +1,165 @@
+package socketclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"pnda/vpp/monitoring/govpp/api"
+	"sync"
+	"testing"
+	"time"
+)
+
+func init() {
+	logrus.SetLevel(logrus.ErrorLevel)
+}
+
+const pingID = 100
+const pingReplyID = 101
+
+func startServer(t *testing.T) (string, chan ([]byte)) {
+	dir, err := ioutil.TempDir("", "testingVppSocket")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	path := filepath.Join(dir, "vpp-api.sock")
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+
+	received := make(chan ([]byte), 10)
+	go func() {
+		defer os.RemoveAll(dir)
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		// Registration
+		if _, err := ReadMsg(conn); err != nil {
+			return
+		}
+		reply, _ := api.EncodeMessage(&api.SockclntCreateReply{
+			Index: 5,
+			Count: 2,
+			MessageTable: []api.MessageTableEntry{
+				{Index: pingID, Name: api.NameWithCrc(&api.ControlPing{})},
+				{Index: pingReplyID, Name: api.NameWithCrc(&api.ControlPingReply{})},
+			},
+		}, 16, 0, 0)
+		WriteMsg(conn, reply)
+
+		// Echo a reply to each ping
+		for {
+			data, err := ReadMsg(conn)
+			if err != nil {
+				return
+			}
+			received <- data
+
+			ctx, _ := api.DecodeContext(data, api.RequestMessage)
+			pingReply, _ := api.EncodeMessage(&api.ControlPingReply{VpePid: 42}, pingReplyID, 0, ctx)
+			WriteMsg(conn, pingReply)
+		}
+	}()
+
+	return path, received
+}
+
+func TestConnectAndExchange(t *testing.T) {
+	path, received := startServer(t)
+
+	client := NewSocketClient(path)
+	replies := make(chan ([]byte), 1)
+	client.SetMsgCallback(func(msgID uint16, data []byte) {
+		if msgID == pingReplyID {
+			replies <- data
+		}
+	})
+
+	if err := client.Connect("test"); err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer client.Disconnect()
+
+	if client.ClientIndex() != 5 {
+		t.Errorf("Unexpected client index: %v", client.ClientIndex())
+	}
+
+	msgID, err := client.GetMsgID("control_ping", (&api.ControlPing{}).GetCrcString())
+	if err != nil || msgID != pingID {
+		t.Fatalf("Unexpected message ID: %v, error: %v", msgID, err)
+	}
+
+	if _, err := client.GetMsgID("control_ping", "00000000"); err == nil {
+		t.Error("Message with an invalid CRC was resolved")
+	}
+
+	data, _ := api.EncodeMessage(&api.ControlPing{}, msgID, client.ClientIndex(), 77)
+	if err := client.SendMsg(data); err != nil {
+		t.Fatalf("Unable to send: %v", err)
+	}
+
+	select {
+	case <-received:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Server did not receive ping")
+	}
+
+	select {
+	case data := <-replies:
+		reply := &api.ControlPingReply{}
+		api.DecodeMessage(data, reply)
+		if ctx, _ := api.DecodeContext(data, api.ReplyMessage); ctx != 77 || reply.VpePid != 42 {
+			t.Errorf("Unexpected reply: %v, context: %v", reply, ctx)
+		}
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Did not receive ping reply")
+	}
+}
+
+func TestConnectNoServer(t *testing.T) {
+	if err := NewSocketClient("/nonexistent/vpp-api.sock").Connect("test"); err == nil {
+		t.Error("Connection to a non existing socket succeeded")
+	}
+}
+
+func TestDisconnectRepeated(t *testing.T) {
+	path, _ := startServer(t)
+
+	client := NewSocketClient(path)
+	if err := client.Connect("test"); err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Disconnect()
+		}()
+	}
+	wg.Wait()
+
+	if err := client.Disconnect(); err != nil {
+		t.Errorf("Repeated disconnect failed: %v", err)
+	}
+}
+
+func TestReadMsgExceedingMaxSize(t *testing.T) {
+	header := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint32(header[8:12], 0xffffffff)
+
+	if _, err := ReadMsg(bytes.NewReader(header)); err == nil {
+		t.Error("Message exceeding maximum size was read")
+	}
+}
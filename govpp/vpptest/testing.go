@@ -0,0 +1,42 @@
+package vpptest
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/govpp"
+	"testing"
+)
+
+// Only tests use the fake VPP, keep their output readable. Test packages may lower the level further in their init.
+func init() {
+	log.SetLevel(log.ErrorLevel)
+}
+
+// Aggregator collecting stats into a buffered channel, so that tests can receive stats published by collectors
+type ChannelAggregator struct {
+	Ch chan (aggregator.Stat)
+}
+
+func NewChannelAggregator(size int) *ChannelAggregator {
+	return &ChannelAggregator{make(chan (aggregator.Stat), size)}
+}
+
+func (s *ChannelAggregator) Channel() chan (aggregator.Stat) {
+	return s.Ch
+}
+
+// Start a fake VPP and connect to it. The connection is disconnected and the fake VPP closed once the test finishes.
+func NewConnectedServer(t testing.TB) (*VppServer, *govpp.VppConnection) {
+	t.Helper()
+
+	server, err := NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	connection := server.Connect("test")
+	t.Cleanup(connection.Disconnect)
+
+	return server, connection
+}
@@ -0,0 +1,514 @@
+/*
+Package vpptest provides an in-process fake VPP serving binary APIs over a unix socket, for testing without VPP.
+*/
+package vpptest
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/socketclient"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const DefaultPid = 4242
+
+// Handles a request from a client, returned messages are sent back as replies (with the request's context)
+type RequestHandler func(clientIndex uint32, request api.Message) []api.Message
+
+// Messages known to the fake VPP. Message IDs are assigned according to the position in this list.
+var knownMessages = []api.Message{
+	&api.SockclntDelete{},
+	&api.SockclntDeleteReply{},
+	&api.ControlPing{},
+	&api.ControlPingReply{},
+	&api.ShowVersion{},
+	&api.ShowVersionReply{},
+	&api.CliInband{},
+	&api.CliInbandReply{},
+	&api.SwInterfaceDump{},
+	&api.SwInterfaceDetails{},
+	&api.WantInterfaceEvents{},
+	&api.WantInterfaceEventsReply{},
+	&api.SwInterfaceEvent{},
+	&api.IpAddressDump{},
+	&api.IpAddressDetails{},
+	&api.WantStats{},
+	&api.WantStatsReply{},
+	&api.VnetInterfaceSimpleCounters{},
+	&api.VnetInterfaceCombinedCounters{},
+	&api.VnetIp4FibCounters{},
+	&api.VnetIp6FibCounters{},
+}
+
+const firstMsgID = socketclient.SockclntCreateMsgID + 2
+
+// Scriptable fake VPP
+type VppServer struct {
+	// Pid reported in control ping replies
+	Pid uint32
+
+	dir      string
+	path     string
+	listener net.Listener
+
+	lock   sync.Mutex
+	msgIDs map[string]uint16
+	// Names under which messages are advertised to clients if different from their name_crc, empty if not advertised
+	advertised map[string]string
+	msgTypes   map[uint16]api.Message
+	handlers   map[string]RequestHandler
+	clients    map[uint32]*vppClient
+	nextClient uint32
+	received   map[string]int
+	version    api.ShowVersionReply
+	interfaces []api.SwInterfaceDetails
+	addresses  []api.IpAddressDetails
+	// Output of CLI commands executed by cli_inband, keyed by command
+	cliOutputs map[string]string
+}
+
+type vppClient struct {
+	conn            net.Conn
+	writeLock       sync.Mutex
+	interfaceEvents bool
+	stats           bool
+}
+
+// Start a fake VPP listening on a socket in a temporary directory
+func NewVppServer() (*VppServer, error) {
+	dir, err := ioutil.TempDir("", "vpptest")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "vpp-api.sock")
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	s := &VppServer{
+		Pid:        DefaultPid,
+		dir:        dir,
+		path:       path,
+		listener:   listener,
+		msgIDs:     make(map[string]uint16),
+		advertised: make(map[string]string),
+		msgTypes:   make(map[uint16]api.Message),
+		handlers:   make(map[string]RequestHandler),
+		clients:    make(map[uint32]*vppClient),
+		received:   make(map[string]int),
+		cliOutputs: make(map[string]string),
+		version: api.ShowVersionReply{
+			Program:        "vpe",
+			Version:        "18.10-release",
+			BuildDate:      "Wed Oct 24 12:00:00 UTC 2018",
+			BuildDirectory: "/w/workspace/vpp",
+		},
+	}
+
+	for i, msg := range knownMessages {
+		s.addMessage(msg, uint16(firstMsgID+i))
+	}
+	s.registerDefaultHandlers()
+
+	go s.accept()
+
+	return s, nil
+}
+
+func (s *VppServer) addMessage(msg api.Message, msgID uint16) {
+	s.msgIDs[api.NameWithCrc(msg)] = msgID
+	s.msgTypes[msgID] = msg
+}
+
+func (s *VppServer) registerDefaultHandlers() {
+	s.handlers[api.NameWithCrc(&api.ControlPing{})] = func(clientIndex uint32, _ api.Message) []api.Message {
+		return []api.Message{&api.ControlPingReply{ClientIndex: clientIndex, VpePid: s.Pid}}
+	}
+	s.handlers[api.NameWithCrc(&api.ShowVersion{})] = func(uint32, api.Message) []api.Message {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		reply := s.version
+		return []api.Message{&reply}
+	}
+	s.handlers[api.NameWithCrc(&api.CliInband{})] = func(_ uint32, request api.Message) []api.Message {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		output, isKnown := s.cliOutputs[string(request.(*api.CliInband).Cmd)]
+		if !isKnown {
+			// VNET_API_ERROR_UNSPECIFIED
+			return []api.Message{&api.CliInbandReply{Retval: -1}}
+		}
+		return []api.Message{&api.CliInbandReply{Length: uint32(len(output)), Reply: []byte(output)}}
+	}
+	s.handlers[api.NameWithCrc(&api.SwInterfaceDump{})] = func(uint32, api.Message) []api.Message {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		var replies []api.Message
+		for i := range s.interfaces {
+			details := s.interfaces[i]
+			replies = append(replies, &details)
+		}
+		return replies
+	}
+	s.handlers[api.NameWithCrc(&api.IpAddressDump{})] = func(_ uint32, request api.Message) []api.Message {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		dump := request.(*api.IpAddressDump)
+		var replies []api.Message
+		for i := range s.addresses {
+			if details := s.addresses[i]; details.SwIfIndex == dump.SwIfIndex && details.IsIpv6 == dump.IsIpv6 {
+				replies = append(replies, &details)
+			}
+		}
+		return replies
+	}
+	s.handlers[api.NameWithCrc(&api.WantInterfaceEvents{})] = func(clientIndex uint32, request api.Message) []api.Message {
+		s.withClient(clientIndex, func(client *vppClient) {
+			client.interfaceEvents = request.(*api.WantInterfaceEvents).EnableDisable == 1
+		})
+		return []api.Message{&api.WantInterfaceEventsReply{}}
+	}
+	s.handlers[api.NameWithCrc(&api.WantStats{})] = func(clientIndex uint32, request api.Message) []api.Message {
+		s.withClient(clientIndex, func(client *vppClient) {
+			client.stats = request.(*api.WantStats).EnableDisable == 1
+		})
+		return []api.Message{&api.WantStatsReply{}}
+	}
+	s.handlers[api.NameWithCrc(&api.SockclntDelete{})] = func(uint32, api.Message) []api.Message {
+		return []api.Message{&api.SockclntDeleteReply{}}
+	}
+}
+
+// Path of the socket to connect to
+func (s *VppServer) SocketPath() string {
+	return s.path
+}
+
+// Connect to this fake VPP
+func (s *VppServer) Connect(name string) *govpp.VppConnection {
+	return govpp.VppConnectionAttempt{
+		Name:      name,
+		Transport: socketclient.NewSocketClient(s.path),
+	}.Connect(context.Background())
+}
+
+// Stop the fake VPP, disconnecting all clients
+func (s *VppServer) Close() {
+	s.listener.Close()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for index, client := range s.clients {
+		client.conn.Close()
+		delete(s.clients, index)
+	}
+	os.RemoveAll(s.dir)
+}
+
+// Drop all client connections, as VPP does when it restarts. New clients are still accepted.
+func (s *VppServer) DisconnectClients() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for index, client := range s.clients {
+		client.conn.Close()
+		delete(s.clients, index)
+	}
+}
+
+// Replace the handler of a request message e.g. to return an error retval or to not reply at all
+func (s *VppServer) RegisterHandler(request api.Message, handler RequestHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.handlers[api.NameWithCrc(request)] = handler
+}
+
+// Advertise msg with a different CRC to clients connecting from now on, simulating an incompatible VPP
+func (s *VppServer) SetMessageCrc(msg api.Message, crc string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.advertised[api.NameWithCrc(msg)] = fmt.Sprintf("%s_%s", msg.GetMessageName(), crc)
+}
+
+// Do not advertise msg to clients connecting from now on, simulating a VPP without msg
+func (s *VppServer) RemoveMessage(msg api.Message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.advertised[api.NameWithCrc(msg)] = ""
+}
+
+// Set the version reported by show_version
+func (s *VppServer) SetVersion(version api.ShowVersionReply) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.version = version
+}
+
+// Set the interfaces reported by sw_interface_dump
+func (s *VppServer) SetInterfaces(interfaces ...api.SwInterfaceDetails) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.interfaces = interfaces
+}
+
+// Set the output of a CLI command executed by cli_inband, unknown commands fail
+func (s *VppServer) SetCliOutput(cmd string, output string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cliOutputs[cmd] = output
+}
+
+// Set the addresses reported by ip_address_dump
+func (s *VppServer) SetIpAddresses(addresses ...api.IpAddressDetails) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.addresses = addresses
+}
+
+// Number of received requests of the same type as request
+func (s *VppServer) Received(request api.Message) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.received[api.NameWithCrc(request)]
+}
+
+// Wait until at least count requests of the same type as request are received
+func (s *VppServer) WaitForRequests(request api.Message, count int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for s.Received(request) < count {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timed out waiting for %v %v requests, received: %v",
+				count, request.GetMessageName(), s.Received(request))
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return nil
+}
+
+// Send an interface state change to all clients subscribed with want_interface_events
+func (s *VppServer) InjectInterfaceEvent(swIfIndex uint32, adminUp bool, linkUp bool, deleted bool) {
+	event := &api.SwInterfaceEvent{
+		SwIfIndex:   swIfIndex,
+		AdminUpDown: boolToU8(adminUp),
+		LinkUpDown:  boolToU8(linkUp),
+		Deleted:     boolToU8(deleted),
+	}
+
+	s.notify(event, func(client *vppClient) bool {
+		return client.interfaceEvents
+	})
+}
+
+// Send a burst of simple (packet) counters to all clients subscribed with want_stats
+func (s *VppServer) InjectSimpleCounters(counterType uint8, firstSwIfIndex uint32, packets ...uint64) {
+	s.InjectCounters(&api.VnetInterfaceSimpleCounters{
+		VnetCounterType: counterType,
+		FirstSwIfIndex:  firstSwIfIndex,
+		Count:           uint32(len(packets)),
+		Data:            packets,
+	})
+}
+
+// Send a burst of combined (packet, byte) counters to all clients subscribed with want_stats
+func (s *VppServer) InjectCombinedCounters(counterType uint8, firstSwIfIndex uint32, packetsAndBytes ...[2]uint64) {
+	data := make([]api.VlibCounter, len(packetsAndBytes))
+	for i, counter := range packetsAndBytes {
+		data[i] = api.VlibCounter{Packets: counter[0], Bytes: counter[1]}
+	}
+
+	s.InjectCounters(&api.VnetInterfaceCombinedCounters{
+		VnetCounterType: counterType,
+		FirstSwIfIndex:  firstSwIfIndex,
+		Count:           uint32(len(packetsAndBytes)),
+		Data:            data,
+	})
+}
+
+// Send a counters notification to all clients subscribed with want_stats
+func (s *VppServer) InjectCounters(counters api.Message) {
+	s.notify(counters, func(client *vppClient) bool {
+		return client.stats
+	})
+}
+
+func (s *VppServer) notify(msg api.Message, filter func(client *vppClient) bool) {
+	s.lock.Lock()
+	var subscribed []uint32
+	for index, client := range s.clients {
+		if filter(client) {
+			subscribed = append(subscribed, index)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, index := range subscribed {
+		s.send(index, msg, 0)
+	}
+}
+
+func (s *VppServer) withClient(clientIndex uint32, lambda func(client *vppClient)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if client, isPresent := s.clients[clientIndex]; isPresent {
+		lambda(client)
+	}
+}
+
+func (s *VppServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *VppServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	clientIndex, err := s.register(conn)
+	if err != nil {
+		log.WithField("error", err).Warn("Fake VPP: client registration failed")
+		return
+	}
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.clients, clientIndex)
+		s.lock.Unlock()
+	}()
+
+	for {
+		data, err := socketclient.ReadMsg(conn)
+		if err != nil {
+			return
+		}
+
+		msgID, _ := api.DecodeMessageID(data)
+
+		s.lock.Lock()
+		prototype, isKnown := s.msgTypes[msgID]
+		s.lock.Unlock()
+		if !isKnown {
+			log.WithField("msg-id", msgID).Warn("Fake VPP: unknown message, ignoring")
+			continue
+		}
+
+		request := reflect.New(reflect.TypeOf(prototype).Elem()).Interface().(api.Message)
+		if err := api.DecodeMessage(data, request); err != nil {
+			log.WithField("error", err).Warn("Fake VPP: invalid message, ignoring")
+			continue
+		}
+		ctx, _ := api.DecodeContext(data, request.GetMessageType())
+
+		s.lock.Lock()
+		handler := s.handlers[api.NameWithCrc(request)]
+		s.lock.Unlock()
+
+		if handler != nil {
+			for _, reply := range handler(clientIndex, request) {
+				s.send(clientIndex, reply, ctx)
+			}
+		}
+
+		// Count the request only after it was handled, so that waiting for it guarantees its effects
+		s.lock.Lock()
+		s.received[api.NameWithCrc(request)]++
+		s.lock.Unlock()
+
+		if _, isDelete := request.(*api.SockclntDelete); isDelete {
+			return
+		}
+	}
+}
+
+func (s *VppServer) register(conn net.Conn) (uint32, error) {
+	data, err := socketclient.ReadMsg(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	if msgID, _ := api.DecodeMessageID(data); msgID != socketclient.SockclntCreateMsgID {
+		return 0, fmt.Errorf("Unexpected registration message: %v", msgID)
+	}
+
+	s.lock.Lock()
+	s.nextClient++
+	clientIndex := s.nextClient
+	s.clients[clientIndex] = &vppClient{conn: conn}
+
+	reply := &api.SockclntCreateReply{Index: clientIndex}
+	for name, msgID := range s.msgIDs {
+		if advertisedName, isPresent := s.advertised[name]; isPresent {
+			if advertisedName == "" {
+				continue
+			}
+			name = advertisedName
+		}
+		reply.MessageTable = append(reply.MessageTable, api.MessageTableEntry{Index: msgID, Name: name})
+	}
+	reply.Count = uint16(len(reply.MessageTable))
+	s.lock.Unlock()
+
+	replyData, err := api.EncodeMessage(reply, socketclient.SockclntCreateMsgID+1, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	return clientIndex, socketclient.WriteMsg(conn, replyData)
+}
+
+func (s *VppServer) send(clientIndex uint32, msg api.Message, ctx uint32) {
+	s.lock.Lock()
+	client, isPresent := s.clients[clientIndex]
+	msgID, isKnown := s.msgIDs[api.NameWithCrc(msg)]
+	s.lock.Unlock()
+
+	if !isPresent || !isKnown {
+		return
+	}
+
+	data, err := api.EncodeMessage(msg, msgID, clientIndex, ctx)
+	if err != nil {
+		log.WithField("error", err).Warn("Fake VPP: unable to encode message")
+		return
+	}
+
+	client.writeLock.Lock()
+	defer client.writeLock.Unlock()
+	socketclient.WriteMsg(client.conn, data)
+}
+
+func boolToU8(value bool) uint8 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package vpptest
+
+import (
+	"context"
+	"pnda/vpp/monitoring/govpp/api"
+	"testing"
+	"time"
+)
+
+func TestConnect(t *testing.T) {
+	server, err := NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	if connection.GetPid() != DefaultPid {
+		t.Errorf("Unexpected pid: %v, expected: %v", connection.GetPid(), DefaultPid)
+	}
+
+	if server.Received(&api.ControlPing{}) != 1 {
+		t.Errorf("Unexpected number of pings: %v", server.Received(&api.ControlPing{}))
+	}
+}
+
+func TestPing(t *testing.T) {
+	server, err := NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+	server.Pid = 7
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	pid, err := connection.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	if pid != 7 {
+		t.Errorf("Unexpected pid: %v", pid)
+	}
+}
+
+func TestScriptedHandler(t *testing.T) {
+	server, err := NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	// Swallow pings
+	server.RegisterHandler(&api.ControlPing{}, func(uint32, api.Message) []api.Message {
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(200))
+	defer cancel()
+
+	if _, err := connection.Ping(ctx); err == nil {
+		t.Error("Received ping reply from a swallowing handler")
+	}
+
+	if server.Received(&api.ControlPing{}) != 2 {
+		t.Errorf("Unexpected number of pings: %v", server.Received(&api.ControlPing{}))
+	}
+}
@@ -1,12 +1,14 @@
 /*
 Package monitoring implements a configurable standalone vpp monitoring agent.
 
-The agent is implemented in pure Go and Cgo. It connects to VPP through its
-shared memory APIs.
+The agent is implemented in pure Go. It connects to VPP through its
+binary APIs exposed over the VPP API unix socket.
 */
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/natefinch/lumberjack"
@@ -15,13 +17,20 @@ import (
 	"os"
 	"pnda/vpp/monitoring/aggregator"
 	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/collector/ifc_registry"
 	"pnda/vpp/monitoring/collector/keepalive"
 	"pnda/vpp/monitoring/config"
 	"pnda/vpp/monitoring/govpp"
 	"pnda/vpp/monitoring/util"
+	"sync"
+	"time"
 )
 
-const CONNECTION_NAME = "vpp-monitoring-agent"
+// Keepalive ping timeout in seconds
+const KEEPALIVE_TIMEOUT = 10
+
+// Delay between keepalive pings in seconds
+var keepaliveInterval uint = 10
 
 func init() {
 	log.SetOutput(os.Stdout)
@@ -64,39 +73,117 @@ func main() {
 		go startProfiling(args)
 	}
 
+	// Each VPP target is monitored independently, sharing aggregators and producers
+	for _, target := range args.Targets(wiringAndConfig) {
+		go monitorTarget(target, args.ConnectRetry, wiringAndConfig.Agent, withOrigin(aggregatorMap, target.VppUuid))
+	}
+
+	select {}
+}
+
+// Connect to a VPP target, run its collectors and reconnect whenever a keepalive fails
+func monitorTarget(target config.TargetWiring, retry govpp.RetryPolicy, agentWiring config.AgentWiring,
+	aggregatorMap map[string](aggregator.CollectorAggregator)) {
+
+	agentAggregator := aggregatorMap[agentWiring.Aggregator]
+	keepaliveAggregator := aggregatorMap[agentWiring.KeepaliveAggregator]
+	var reconnection vppReconnection
+	// API metrics survive reconnects, so that failures leading to a reconnect stay visible
+	if target.Connection.Metrics == nil {
+		target.Connection.Metrics = govpp.NewApiMetrics()
+	}
+
 	for {
-		log.Info("Starting VPP monitoring agent")
-		connAttempt := &govpp.VppConnectionAttempt{Name: CONNECTION_NAME}
-		connection := connAttempt.Connect()
+		log.WithField("target", target.Name).Info("Starting VPP monitoring")
+		connection, err := connect(target.Connection, retry, aggregatorMap)
+		if err != nil {
+			// Only this target is given up, other targets keep being monitored
+			log.WithFields(log.Fields{
+				"target": target.Name,
+				"error":  err,
+			}).Error("Giving up connecting to VPP, target no longer monitored")
+			return
+		}
+		activeConnections.set(target.Name, connection)
+
+		if reconnection.RestartCount > 0 {
+			reconnection.LastReconnect = time.Now()
+			log.WithFields(log.Fields{
+				"target":       target.Name,
+				"reconnection": util.StringOf(reconnection),
+			}).Info("Reconnected to VPP")
+			if agentAggregator != nil {
+				agentAggregator.Channel() <- reconnection
+			}
+		}
 
-		keepaliveFailureCh := make(chan (int))
+		keepaliveFailureCh := make(chan (int), 1)
+		vppRestartCh := make(chan (uint), 1)
 		keepaliveStopCh := make(chan (int))
 		keepaliveExec := keepalive.KeepaliveCollectorConfiguration{
 			Name:    "Keepalive-executor",
-			Timeout: 10,
-		}.Create(keepaliveFailureCh)
-		collector.CollectScheduled(connection, keepaliveExec, 10, keepaliveStopCh)
+			Timeout: KEEPALIVE_TIMEOUT,
+		}.Create(keepaliveAggregator, keepaliveFailureCh, vppRestartCh)
+		// Keepalive is never retried, a failure is signalled right away
+		keepaliveExecution := collector.Execution{
+			Name:    "Keepalive-executor",
+			Target:  target.Name,
+			Timeout: time.Second * KEEPALIVE_TIMEOUT,
+		}
+		collector.CollectScheduled(connection, keepaliveExec, keepaliveInterval, keepaliveExecution, keepaliveStopCh)
 
 		var collectorExecutionStopChannels [](chan (int))
 		// Put the first stop channel (for keepalive) in
 		collectorExecutionStopChannels = append(collectorExecutionStopChannels, keepaliveStopCh)
 		var createdCollectors []collector.Collector
+		// Keepalive has to be closed as well, so that it can be recreated after reconnect
+		createdCollectors = append(createdCollectors, keepaliveExec)
+		// Collectors executed just once, to be executed again if VPP restarts
+		var onceCollectors []wiredCollector
+
+		compatibility := vppApiCompatibility{Messages: connection.Compatibility()}
 
-		for _, clctrWiringAndConfig := range wiringAndConfig.Collectors {
+		for _, clctrWiringAndConfig := range target.Collectors {
 			clctr := clctrWiringAndConfig.Config.Create(aggregatorMap[clctrWiringAndConfig.Aggregator])
 			createdCollectors = append(createdCollectors, clctr)
 
+			if err := collector.CheckCompatibility(connection, clctr); err != nil {
+				log.WithFields(log.Fields{
+					"target":    target.Name,
+					"component": clctrWiringAndConfig.Name,
+					"error":     err,
+				}).Error("Collector incompatible with connected VPP, not executing")
+				compatibility.RefusedCollectors = append(compatibility.RefusedCollectors, clctrWiringAndConfig.Name)
+				continue
+			}
+
 			stopCh := scheduleCollector(clctrWiringAndConfig, connection, clctr)
 			if stopCh != nil {
 				collectorExecutionStopChannels = append(collectorExecutionStopChannels, stopCh)
+			} else {
+				onceCollectors = append(onceCollectors, wiredCollector{clctrWiringAndConfig, clctr})
 			}
 		}
 
-		// Block until a keepalive fails
-		<-keepaliveFailureCh
-		close(keepaliveFailureCh)
+		if agentAggregator != nil {
+			agentAggregator.Channel() <- compatibility
+		}
 
-		log.Error("Keepalive failure detected, reconnecting VPP and reinitializing collectors")
+		// Block until a keepalive fails, resync collectors on each VPP restart meanwhile
+	events:
+		for {
+			select {
+			case <-keepaliveFailureCh:
+				break events
+			case pid := <-vppRestartCh:
+				handleVppRestart(connection, pid, onceCollectors, aggregatorMap)
+			}
+		}
+
+		log.WithField("target", target.Name).
+			Error("Keepalive failure detected, reconnecting VPP and reinitializing collectors")
+		// Reported right away, a quick reconnect would hide the outage otherwise
+		publishConnectionState(aggregatorMap, vppConnectionState{Connected: false, Error: "Keepalive failed"})
 
 		log.Info("Stopping all collector executions")
 		for _, stopChannel := range collectorExecutionStopChannels {
@@ -107,11 +194,86 @@ func main() {
 			createdCollector.Close()
 		}
 
-		// This reconnect loop only works in theory, because with VPP disconnect and connect still crash the program
-		// so an external loop is needed to restart the agent
-		//connection.Disconnect()
-		// so instead of disconnect and loop, just return -2 as an indication to the external loop
-		os.Exit(100)
+		// Aggregators and producers keep running, only the VPP connection is reestablished
+		activeConnections.set(target.Name, nil)
+		// Stops background refreshes still using the connection
+		ifc_registry.Release(connection)
+		connection.Disconnect()
+		reconnection.RestartCount++
+	}
+}
+
+// Collector together with its wiring
+type wiredCollector struct {
+	wiring    config.CollectorWiring
+	collector collector.Collector
+}
+
+// Agent stat reporting a VPP restart detected by a change of its pid. Published into all aggregators, since
+// the state collected from the previous VPP instance is no longer valid.
+type vppRestarted struct {
+	PreviousPid uint `json:"previous_pid"`
+	Pid         uint `json:"pid"`
+}
+
+// Re-run once and notification collectors after VPP restarted with a new pid. VPP loses all state of its
+// clients on restart, so the collectors have to re-collect static data and re-subscribe for notifications.
+func handleVppRestart(connection *govpp.VppConnection, pid uint, onceCollectors []wiredCollector,
+	aggregatorMap map[string](aggregator.CollectorAggregator)) {
+
+	restart := vppRestarted{PreviousPid: connection.GetPid(), Pid: pid}
+	log.WithField("restart", util.StringOf(restart)).Warn("VPP restart detected, resynchronizing collectors")
+
+	connection.SetPid(pid)
+	for _, aggr := range aggregatorMap {
+		aggr.Channel() <- restart
+	}
+
+	for _, onceCollector := range onceCollectors {
+		collector.CollectOnce(connection, onceCollector.collector, onceCollector.wiring.Execution())
+	}
+}
+
+// Agent stat reporting compatibility of VPP API messages known to the agent with connected VPP, together with
+// collectors not executed due to incompatible messages
+type vppApiCompatibility struct {
+	Messages          map[string]govpp.MessageCompatibility `json:"messages"`
+	RefusedCollectors []string                              `json:"refused_collectors"`
+}
+
+// Agent stat reporting in-process reconnections to VPP
+type vppReconnection struct {
+	RestartCount  uint      `json:"restart_count"`
+	LastReconnect time.Time `json:"last_reconnect"`
+}
+
+// Agent stat reporting the state of the connection to VPP. Published into all aggregators, so that consumers
+// know the agent is alive even if VPP is not.
+type vppConnectionState struct {
+	Connected bool   `json:"connected"`
+	Attempt   uint   `json:"attempt,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Connect to VPP, retrying according to retry policy. Returns an error once all attempts failed, the final
+// disconnected state is published before returning.
+func connect(connAttempt govpp.VppConnectionAttempt, retry govpp.RetryPolicy,
+	aggregatorMap map[string](aggregator.CollectorAggregator)) (*govpp.VppConnection, error) {
+	connection, err := connAttempt.ConnectWithRetry(context.Background(), retry, func(attempt uint, err error) {
+		publishConnectionState(aggregatorMap, vppConnectionState{Connected: false, Attempt: attempt, Error: err.Error()})
+	})
+	if err != nil {
+		publishConnectionState(aggregatorMap, vppConnectionState{Connected: false, Error: err.Error()})
+		return nil, err
+	}
+
+	publishConnectionState(aggregatorMap, vppConnectionState{Connected: true})
+	return connection, nil
+}
+
+func publishConnectionState(aggregatorMap map[string](aggregator.CollectorAggregator), state vppConnectionState) {
+	for _, aggr := range aggregatorMap {
+		aggr.Channel() <- state
 	}
 }
 
@@ -125,6 +287,17 @@ func createAggregators(wiringAndConfig config.WiringConfiguration) map[string](a
 	return aggregatorMap
 }
 
+// Wrap each aggregator, so that stats sent through the wrappers are tagged with uuid of their VPP
+func withOrigin(aggregatorMap map[string](aggregator.Aggregator),
+	uuid aggregator.VppUuid) map[string](aggregator.CollectorAggregator) {
+
+	originMap := make(map[string](aggregator.CollectorAggregator))
+	for name, aggr := range aggregatorMap {
+		originMap[name] = aggregator.WithOrigin(aggr, uuid)
+	}
+	return originMap
+}
+
 func startAggregators(aggregatorMap map[string](aggregator.Aggregator), uuid aggregator.VppUuid) {
 	for _, aggr := range aggregatorMap {
 		aggr.Start(uuid)
@@ -139,18 +312,59 @@ func createAndStartProducers(wiringAndConfig config.WiringConfiguration, aggrega
 }
 
 // Go to http://localhost:<debug-port>/debug/pprof/ to evaluate profiling results
+// and to http://localhost:<debug-port>/debug/vpp-api/ to see metrics of VPP API calls per target
+// or to http://localhost:<debug-port>/debug/collectors/ to see failed executions per target and collector
 func startProfiling(args config.Args) {
 	log.Info("Exposing profiling information")
+	http.HandleFunc("/debug/vpp-api/", serveApiStats)
+	http.HandleFunc("/debug/collectors/", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(collector.Failures())
+	})
 	http.ListenAndServe(fmt.Sprintf(":%v", args.ProfilePort), http.DefaultServeMux)
 }
 
+// Connections to all monitored VPP targets, keyed by target name
+type connectionRegistry struct {
+	lock        sync.Mutex
+	connections map[string]*govpp.VppConnection
+}
+
+var activeConnections = connectionRegistry{connections: make(map[string]*govpp.VppConnection)}
+
+func (s *connectionRegistry) set(target string, connection *govpp.VppConnection) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if connection == nil {
+		delete(s.connections, target)
+	} else {
+		s.connections[target] = connection
+	}
+}
+
+// Serve metrics of VPP API calls of all currently connected targets as JSON
+func serveApiStats(writer http.ResponseWriter, _ *http.Request) {
+	activeConnections.lock.Lock()
+	stats := make(map[string](map[string]govpp.MessageMetrics))
+	for target, connection := range activeConnections.connections {
+		stats[target] = connection.Metrics()
+	}
+	activeConnections.lock.Unlock()
+
+	writer.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(writer).Encode(stats); err != nil {
+		log.WithField("error", err).Warn("Unable to serve VPP API stats")
+	}
+}
+
 func scheduleCollector(clctrWiringAndConfig config.CollectorWiring, connection *govpp.VppConnection, clctr collector.Collector) chan (int) {
 	switch clctrWiringAndConfig.Scheduling.SchedulingType {
 
 	case collector.NOTIFICATION_SCHEDULING:
 		fallthrough
 	case collector.ONCE_SCHEDULING:
-		collector.CollectOnce(connection, clctr)
+		collector.CollectOnce(connection, clctr, clctrWiringAndConfig.Execution())
 		return nil
 	case collector.REPEATED_SCHEDULING:
 		if clctrWiringAndConfig.Scheduling.SchedulingDelay < 1 {
@@ -161,7 +375,7 @@ func scheduleCollector(clctrWiringAndConfig config.CollectorWiring, connection *
 		}
 		stopCh := make(chan (int))
 		collector.CollectScheduled(connection, clctr,
-			clctrWiringAndConfig.Scheduling.SchedulingDelay, stopCh)
+			clctrWiringAndConfig.Scheduling.SchedulingDelay, clctrWiringAndConfig.Execution(), stopCh)
 		return stopCh
 	default:
 		log.WithFields(log.Fields{
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/ghodss/yaml"
+	"io/ioutil"
+	"os"
+	"pnda/vpp/monitoring/aggregator"
+	"pnda/vpp/monitoring/collector"
+	"pnda/vpp/monitoring/collector/ifc_state"
+	"pnda/vpp/monitoring/config"
+	"pnda/vpp/monitoring/govpp"
+	"pnda/vpp/monitoring/govpp/api"
+	"pnda/vpp/monitoring/govpp/vpptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	log.SetLevel(log.ErrorLevel)
+}
+
+const pipelineWiring = `
+Collectors:
+  Version:
+    Type: version.Version
+    Schedule:
+      Type: once
+    Aggregator: Test-aggregator
+  Interface-state-notifications:
+    Type: ifc_state.InterfaceStateChanges
+    Schedule:
+      Type: notifications
+    Aggregator: Test-aggregator
+Aggregators:
+  Test-aggregator:
+    Type: aggregator.Buffered
+    Configuration:
+      InboundBufferSize: 20
+      OutboundBufferSize: 1
+Producers:
+  Json-file:
+    Type: producer.File
+    Configuration:
+      Format: json
+      FileName: %v
+      FileSize: 1
+    Aggregator: Test-aggregator
+`
+
+// Exercise collectors, aggregators and producers wired together against a fake VPP
+func TestPipeline(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	output, err := ioutil.TempFile("", "testingVppPipeline")
+	if err != nil {
+		t.Fatalf("Unable to create temp file: %v", err)
+	}
+	output.Close()
+	defer os.Remove(output.Name())
+
+	var wiring config.WiringInput
+	if err := yaml.Unmarshal([]byte(fmt.Sprintf(pipelineWiring, output.Name())), &wiring); err != nil {
+		t.Fatalf("Unable to parse wiring: %v", err)
+	}
+	wiringAndConfig := wiring.Parse()
+
+	aggregatorMap := createAggregators(wiringAndConfig)
+	createAndStartProducers(wiringAndConfig, aggregatorMap)
+	startAggregators(aggregatorMap, "vpp-test")
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	for _, clctrWiringAndConfig := range wiringAndConfig.Collectors {
+		clctr := clctrWiringAndConfig.Config.Create(aggregatorMap[clctrWiringAndConfig.Aggregator])
+		defer clctr.Close()
+		scheduleCollector(clctrWiringAndConfig, connection, clctr)
+	}
+
+	if err := server.WaitForRequests(&api.WantInterfaceEvents{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+	server.InjectInterfaceEvent(3, true, true, false)
+
+	expected := []string{`"stat_type":"version.version"`, `"stat_type":"ifc_state.interfaceStateChange"`,
+		`"vpp_uuid":"vpp-test"`, `"interface_index":3`}
+
+	deadline := time.Now().Add(time.Second * time.Duration(5))
+	for {
+		content, _ := ioutil.ReadFile(output.Name())
+
+		missing := ""
+		for _, e := range expected {
+			if !strings.Contains(string(content), e) {
+				missing = e
+				break
+			}
+		}
+
+		if missing == "" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out. Produced output is missing %v: %s", missing, content)
+		}
+		time.Sleep(time.Millisecond * time.Duration(50))
+	}
+}
+
+// Once and notification collectors are executed again after VPP restart
+func TestVppRestartResync(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	output, err := ioutil.TempFile("", "testingVppRestart")
+	if err != nil {
+		t.Fatalf("Unable to create temp file: %v", err)
+	}
+	output.Close()
+	defer os.Remove(output.Name())
+
+	var wiring config.WiringInput
+	if err := yaml.Unmarshal([]byte(fmt.Sprintf(pipelineWiring, output.Name())), &wiring); err != nil {
+		t.Fatalf("Unable to parse wiring: %v", err)
+	}
+	wiringAndConfig := wiring.Parse()
+
+	aggregatorMap := createAggregators(wiringAndConfig)
+	createAndStartProducers(wiringAndConfig, aggregatorMap)
+	startAggregators(aggregatorMap, "vpp-test")
+
+	connection := server.Connect("test")
+	defer connection.Disconnect()
+
+	var onceCollectors []wiredCollector
+	for _, clctrWiringAndConfig := range wiringAndConfig.Collectors {
+		clctr := clctrWiringAndConfig.Config.Create(aggregatorMap[clctrWiringAndConfig.Aggregator])
+		defer clctr.Close()
+		scheduleCollector(clctrWiringAndConfig, connection, clctr)
+		onceCollectors = append(onceCollectors, wiredCollector{clctrWiringAndConfig, clctr})
+	}
+
+	handleVppRestart(connection, vpptest.DefaultPid+1, onceCollectors, withOrigin(aggregatorMap, "vpp-test"))
+
+	if connection.GetPid() != vpptest.DefaultPid+1 {
+		t.Errorf("Connection pid not updated: %v", connection.GetPid())
+	}
+	if err := server.WaitForRequests(&api.ShowVersion{}, 2, time.Second*time.Duration(5)); err != nil {
+		t.Error(err)
+	}
+	if err := server.WaitForRequests(&api.WantInterfaceEvents{}, 2, time.Second*time.Duration(5)); err != nil {
+		t.Error(err)
+	}
+}
+
+const targetsWiring = `
+Targets:
+  First:
+    Uuid: first
+    Socket: %v
+    Collectors:
+      Version:
+        Type: version.Version
+        Schedule:
+          Type: once
+        Aggregator: Test-aggregator
+  Second:
+    Uuid: second
+    Socket: %v
+    Collectors:
+      Version:
+        Type: version.Version
+        Schedule:
+          Type: once
+        Aggregator: Test-aggregator
+Aggregators:
+  Test-aggregator:
+    Type: aggregator.Filtering
+    Configuration:
+      InboundBufferSize: 20
+      OutboundBufferSize: 1
+Producers:
+  Json-file:
+    Type: producer.File
+    Configuration:
+      Format: json
+      FileName: %v
+      FileSize: 1
+    Aggregator: Test-aggregator
+`
+
+// Stats of multiple VPP targets sharing an aggregator are tagged with uuid of their VPP
+func TestMultipleTargets(t *testing.T) {
+	var servers []*vpptest.VppServer
+	for _, version := range []string{"first-version", "second-version"} {
+		server, err := vpptest.NewVppServer()
+		if err != nil {
+			t.Fatalf("Unable to start fake VPP: %v", err)
+		}
+		defer server.Close()
+		server.SetVersion(api.ShowVersionReply{Program: "vpe", Version: version})
+		servers = append(servers, server)
+	}
+
+	output, err := ioutil.TempFile("", "testingVppTargets")
+	if err != nil {
+		t.Fatalf("Unable to create temp file: %v", err)
+	}
+	output.Close()
+	defer os.Remove(output.Name())
+
+	var wiring config.WiringInput
+	wiringContent := fmt.Sprintf(targetsWiring, servers[0].SocketPath(), servers[1].SocketPath(), output.Name())
+	if err := yaml.Unmarshal([]byte(wiringContent), &wiring); err != nil {
+		t.Fatalf("Unable to parse wiring: %v", err)
+	}
+	wiringAndConfig := wiring.Parse()
+
+	aggregatorMap := createAggregators(wiringAndConfig)
+	createAndStartProducers(wiringAndConfig, aggregatorMap)
+	startAggregators(aggregatorMap, "")
+
+	targets := config.Args{}.Targets(wiringAndConfig)
+	if len(targets) != 2 {
+		t.Fatalf("Unexpected targets: %v", targets)
+	}
+
+	for _, target := range targets {
+		connection := target.Connection.Connect(context.Background())
+		defer connection.Disconnect()
+
+		targetAggregators := withOrigin(aggregatorMap, target.VppUuid)
+		for _, clctrWiringAndConfig := range target.Collectors {
+			clctr := clctrWiringAndConfig.Config.Create(targetAggregators[clctrWiringAndConfig.Aggregator])
+			defer clctr.Close()
+			scheduleCollector(clctrWiringAndConfig, connection, clctr)
+		}
+	}
+
+	expected := []*regexp.Regexp{
+		regexp.MustCompile(`"vpp_uuid":"vpp-first"[^}]*"version":"first-version"`),
+		regexp.MustCompile(`"vpp_uuid":"vpp-second"[^}]*"version":"second-version"`),
+	}
+
+	deadline := time.Now().Add(time.Second * time.Duration(5))
+	for {
+		content, _ := ioutil.ReadFile(output.Name())
+
+		var missing *regexp.Regexp
+		for _, e := range expected {
+			if !e.Match(content) {
+				missing = e
+				break
+			}
+		}
+
+		if missing == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out. Produced output is missing %v: %s", missing, content)
+		}
+		time.Sleep(time.Millisecond * time.Duration(50))
+	}
+}
+
+// A target failing all its connection attempts is given up, instead of stopping the whole agent
+func TestMonitorTargetGivesUp(t *testing.T) {
+	aggr := vpptest.NewChannelAggregator(10)
+	target := config.TargetWiring{
+		Name:       "unreachable",
+		Connection: govpp.VppConnectionAttempt{Name: "test", SocketPath: "/nonexistent/vpp-api.sock"},
+	}
+	retry := govpp.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	done := make(chan (int))
+	go func() {
+		monitorTarget(target, retry, config.AgentWiring{Aggregator: "agent"},
+			map[string](aggregator.CollectorAggregator){"agent": aggr})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * time.Duration(5)):
+		t.Fatal("Timed out. Target not given up")
+	}
+
+	// One state per failed attempt followed by the final state
+	var states []vppConnectionState
+	for len(aggr.Ch) > 0 {
+		states = append(states, (<-aggr.Ch).(vppConnectionState))
+	}
+	if len(states) != 3 || states[0].Attempt != 1 || states[1].Attempt != 2 {
+		t.Fatalf("Unexpected connection states: %v", states)
+	}
+	if final := states[2]; final.Connected || final.Error == "" {
+		t.Errorf("Unexpected final connection state: %v", final)
+	}
+}
+
+// A keepalive failure is reported, the target is reconnected in-process and its notification collectors
+// subscribe again
+func TestMonitorTargetReconnects(t *testing.T) {
+	server, err := vpptest.NewVppServer()
+	if err != nil {
+		t.Fatalf("Unable to start fake VPP: %v", err)
+	}
+	defer server.Close()
+
+	previousInterval := keepaliveInterval
+	keepaliveInterval = 1
+	defer func() { keepaliveInterval = previousInterval }()
+
+	aggr := vpptest.NewChannelAggregator(1000)
+	target := config.TargetWiring{
+		Name:       "reconnecting",
+		Connection: govpp.VppConnectionAttempt{Name: "test", SocketPath: server.SocketPath()},
+		Collectors: []config.CollectorWiring{{
+			Name:       "Interface-state-notifications",
+			Config:     ifc_state.InterfaceStateChangesCollectorConfiguration{Name: "Interface-state-notifications"},
+			Scheduling: config.Scheduling{SchedulingType: collector.NOTIFICATION_SCHEDULING},
+			Aggregator: "agent",
+		}},
+	}
+	retry := govpp.RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond}
+	go monitorTarget(target, retry, config.AgentWiring{Aggregator: "agent"},
+		map[string](aggregator.CollectorAggregator){"agent": aggr})
+
+	if err := server.WaitForRequests(&api.WantInterfaceEvents{}, 1, time.Second*time.Duration(5)); err != nil {
+		t.Fatal(err)
+	}
+	server.DisconnectClients()
+
+	// Disconnected state is reported before the target is connected again
+	var states []vppConnectionState
+	var reconnection *vppReconnection
+	deadline := time.After(time.Second * time.Duration(10))
+	for reconnection == nil {
+		select {
+		case stat := <-aggr.Ch:
+			switch typed := stat.(type) {
+			case vppConnectionState:
+				states = append(states, typed)
+			case vppReconnection:
+				reconnection = &typed
+			}
+		case <-deadline:
+			t.Fatalf("Timed out. Reconnection not reported, connection states: %v", states)
+		}
+	}
+
+	if len(states) != 3 || !states[0].Connected || states[1].Connected || !states[2].Connected {
+		t.Errorf("Unexpected connection states: %v", states)
+	}
+	if reconnection.RestartCount != 1 || reconnection.LastReconnect.IsZero() {
+		t.Errorf("Unexpected reconnection: %v", *reconnection)
+	}
+	if err := server.WaitForRequests(&api.WantInterfaceEvents{}, 2, time.Second*time.Duration(5)); err != nil {
+		t.Errorf("Notification collector not subscribed again: %v", err)
+	}
+}
@@ -4,15 +4,6 @@ UUID=`hostid`
 echo "Starting VPP monitoring agent with UUID for VPP:"
 echo $UUID
 
-STATUS=100
-
-while [ $STATUS -eq 100 ]
-do
-  $(dirname $0)/vpp-monitoring-agent -debug -wiring-file=$(dirname $0)/vpp-monitoring-agent-configuration.yaml -vpp-uuid=$UUID
-  STATUS=$?
-  echo "Vpp-monitoring-agent exited with status: $STATUS"
-  if [ $STATUS -eq 100 ]
-  then
-    echo "Restarting..."
-  fi
-done
\ No newline at end of file
+# The agent reconnects to VPP on its own after a keepalive failure, no restart loop is needed
+$(dirname $0)/vpp-monitoring-agent -debug -wiring-file=$(dirname $0)/vpp-monitoring-agent-configuration.yaml -vpp-uuid=$UUID
+echo "Vpp-monitoring-agent exited with status: $?"